@@ -308,6 +308,7 @@ func (a *Asm) Imulq(op Operand) { a.op1("IMULQ", op) }
 func (a *Asm) Incq(op Operand)  { a.op1("INCQ", op) }
 func (a *Asm) Je(name label)    { a.op1("JE", name) }
 func (a *Asm) Jmp(name label)   { a.op1("JMP", name) }
+func (a *Asm) Jle(name label)   { a.op1("JLE", name) }
 func (a *Asm) Jne(name label)   { a.op1("JNE", name) }
 func (a *Asm) Mulq(op Operand)  { a.op1("MULQ", op) }
 func (a *Asm) Neg(op Operand)   { a.op1("NEGQ", op) }
@@ -320,25 +321,47 @@ func (a *Asm) Leaq(opa, opb Operand)       { a.op2("LEAQ", opa, opb) }
 func (a *Asm) Movb(opa, opb Operand)       { a.op2("MOVB", opa, opb) }
 func (a *Asm) Movbqzx(opa, opb Operand)    { a.op2("MOVBQZX", opa, opb) }
 func (a *Asm) Movd(opa, opb Operand)       { a.op2("MOVL", opa, opb) }
+func (a *Asm) Movlqsx(opa, opb Operand)    { a.op2("MOVLQSX", opa, opb) }
 func (a *Asm) Movo(opa, opb Operand)       { a.op2("MOVO", opa, opb) }
 func (a *Asm) Movou(opa, opb Operand)      { a.op2("MOVOU", opa, opb) }
 func (a *Asm) Movq(opa, opb Operand)       { a.op2("MOVQ", opa, opb) }
+func (a *Asm) Movw(opa, opb Operand)       { a.op2("MOVW", opa, opb) }
 func (a *Asm) Movwqsx(opa, opb Operand)    { a.op2("MOVWQSX", opa, opb) }
 func (a *Asm) Orq(opa, opb Operand)        { a.op2("ORQ", opa, opb) }
 func (a *Asm) Packssdw(opa, opb Operand)   { a.op2("PACKSSLW", opa, opb) }
+func (a *Asm) Packsswb(opa, opb Operand)   { a.op2("PACKSSWB", opa, opb) }
 func (a *Asm) Packuswb(opa, opb Operand)   { a.op2("PACKUSWB", opa, opb) }
 func (a *Asm) Paddd(opa, opb Operand)      { a.op2("PADDL", opa, opb) }
+func (a *Asm) Paddw(opa, opb Operand)      { a.op2("PADDW", opa, opb) }
+func (a *Asm) Pand(opa, opb Operand)       { a.op2("PAND", opa, opb) }
+func (a *Asm) Pandn(opa, opb Operand)      { a.op2("PANDN", opa, opb) }
+func (a *Asm) Pcmpeqb(opa, opb Operand)    { a.op2("PCMPEQB", opa, opb) }
+func (a *Asm) Pcmpgtw(opa, opb Operand)    { a.op2("PCMPGTW", opa, opb) }
 func (a *Asm) Pmaddwd(opa, opb Operand)    { a.op2("PMADDWL", opa, opb) }
+func (a *Asm) Pmaxub(opa, opb Operand)     { a.op2("PMAXUB", opa, opb) }
+func (a *Asm) Pminub(opa, opb Operand)     { a.op2("PMINUB", opa, opb) }
+func (a *Asm) Pmulhw(opa, opb Operand)     { a.op2("PMULHW", opa, opb) }
+func (a *Asm) Pmullw(opa, opb Operand)     { a.op2("PMULLW", opa, opb) }
+func (a *Asm) Por(opa, opb Operand)        { a.op2("POR", opa, opb) }
+func (a *Asm) Psllw(opa, opb Operand)      { a.op2("PSLLW", opa, opb) }
 func (a *Asm) Psrad(opa, opb Operand)      { a.op2("PSRAL", opa, opb) }
+func (a *Asm) Psrlw(opa, opb Operand)      { a.op2("PSRLW", opa, opb) }
+func (a *Asm) Psubw(opa, opb Operand)      { a.op2("PSUBW", opa, opb) }
 func (a *Asm) Punpckhbw(opa, opb Operand)  { a.op2("PUNPCKHBW", opa, opb) }
 func (a *Asm) Punpckhqdq(opa, opb Operand) { a.op2("PUNPCKHQDQ", opa, opb) }
 func (a *Asm) Punpcklbw(opa, opb Operand)  { a.op2("PUNPCKLBW", opa, opb) }
+func (a *Asm) Punpckhdq(opa, opb Operand)  { a.op2("PUNPCKHLQ", opa, opb) }
 func (a *Asm) Punpckldq(opa, opb Operand)  { a.op2("PUNPCKLLQ", opa, opb) }
+func (a *Asm) Punpckhwd(opa, opb Operand)  { a.op2("PUNPCKHWL", opa, opb) }
+func (a *Asm) Punpcklwd(opa, opb Operand)  { a.op2("PUNPCKLWL", opa, opb) }
 func (a *Asm) Punpcklqdq(opa, opb Operand) { a.op2("PUNPCKLQDQ", opa, opb) }
 func (a *Asm) Pxor(opa, opb Operand)       { a.op2("PXOR", opa, opb) }
+func (a *Asm) Pslldq(opa, opb Operand)     { a.op2("PSLLO", opa, opb) }
+func (a *Asm) Psrldq(opa, opb Operand)     { a.op2("PSRLO", opa, opb) }
 func (a *Asm) Shlq(opa, opb Operand)       { a.op2("SHLQ", opa, opb) }
 func (a *Asm) Shrq(opa, opb Operand)       { a.op2("SHRQ", opa, opb) }
 func (a *Asm) Subq(opa, opb Operand)       { a.op2("SUBQ", opa, opb) }
+func (a *Asm) Testq(opa, opb Operand)      { a.op2("TESTQ", opa, opb) }
 
 func (a *Asm) Pinsrw(opa, opb, opc Operand) { a.op3("PINSRW", opa, opb, opc) }
 func (a *Asm) Shufps(opa, opb, opc Operand) { a.op3("SHUFPS", opa, opb, opc) }
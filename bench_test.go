@@ -119,3 +119,80 @@ func BenchmarkHorizontalScaler12Go(b *testing.B)  { benchScaler(b, false, false,
 func BenchmarkHorizontalScaler12Asm(b *testing.B) { benchScaler(b, true, false, 12) }
 func BenchmarkHorizontalScalerNGo(b *testing.B)   { benchScaler(b, false, false, 14) }
 func BenchmarkHorizontalScalerNAsm(b *testing.B)  { benchScaler(b, true, false, 14) }
+
+// benchNarrowScaler resizes planes from 1 to 15 pixels wide, where simd
+// scalers only use partial loads & stores
+func benchNarrowScaler(b *testing.B, asm, vertical bool) {
+	n := 64
+	src := make([]byte, n*n)
+	dst := make([]byte, n*n*2)
+	resizers := []Resizer{}
+	for width := 1; width < 16; width++ {
+		cfg := ResizerConfig{
+			Input:      n,
+			Output:     width,
+			Vertical:   vertical,
+			Threads:    1,
+			DisableAsm: !asm,
+		}
+		if vertical {
+			cfg.Output = n * 2
+		}
+		resizers = append(resizers, NewResize(&cfg, NewBicubicFilter()))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, resizer := range resizers {
+			width := j + 1
+			if vertical {
+				resizer.Resize(dst, src, width, n, width, width)
+			} else {
+				resizer.Resize(dst, src, n, n, width, n)
+			}
+		}
+	}
+}
+
+func BenchmarkNarrowHorizontalScalerGo(b *testing.B)  { benchNarrowScaler(b, false, false) }
+func BenchmarkNarrowHorizontalScalerAsm(b *testing.B) { benchNarrowScaler(b, true, false) }
+func BenchmarkNarrowVerticalScalerGo(b *testing.B)    { benchNarrowScaler(b, false, true) }
+func BenchmarkNarrowVerticalScalerAsm(b *testing.B)   { benchNarrowScaler(b, true, true) }
+
+// benchSharpen resizes & sharpens planes, either with the converter or with
+// external full plane passes after the converter
+func benchSharpen(b *testing.B, bt BenchType, external bool) {
+	raw := readImage(b, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, bt.win, bt.hin), image.YCbCrSubsampleRatio420)
+	convert(b, src, raw, true, bt.interlaced, bt.filter)
+	dst := image.NewYCbCr(image.Rect(0, 0, bt.wout, bt.hout), image.YCbCrSubsampleRatio420)
+	sharpen := Sharpen{Amount: 0.5, Radius: 1}
+	cfg, err := PrepareConversion(dst, src)
+	expect(b, err, nil)
+	cfg.Threads = 1
+	if !external {
+		cfg.Sharpen = sharpen
+	}
+	converter, err := NewConverter(cfg, bt.filter)
+	expect(b, err, nil)
+	_, planes, err := inspect(dst, false)
+	expect(b, err, nil)
+	sharpeners := []*externalSharpener{}
+	for i := range planes {
+		sharpeners = append(sharpeners, newExternalSharpener(&planes[i], &sharpen, false, true, 1))
+	}
+	b.SetBytes(int64(bt.wout*bt.hout*3) >> 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		converter.Convert(dst, src)
+		if external {
+			for j, s := range sharpeners {
+				s.sharpen(&planes[j])
+			}
+		}
+	}
+}
+
+func BenchmarkSharpenUp(b *testing.B)           { benchSharpen(b, benchs[1], false) }
+func BenchmarkSharpenUpExternal(b *testing.B)   { benchSharpen(b, benchs[1], true) }
+func BenchmarkSharpenDown(b *testing.B)         { benchSharpen(b, benchs[4], false) }
+func BenchmarkSharpenDownExternal(b *testing.B) { benchSharpen(b, benchs[4], true) }
@@ -0,0 +1,71 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+const (
+	// cancelRows is the number of rows scaled between cancellation checks
+	cancelRows = 16
+)
+
+// canceled returns whether done is closed, a nil done is never closed
+func canceled(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// cancelResizer is implemented by resizers able to stop early once done is
+// closed, leaving dst partially resized
+type cancelResizer interface {
+	resize(done <-chan struct{}, dst, src []byte, width, height, dp, sp int)
+}
+
+// resizeWith resizes with r, stopping early on done if r supports it
+func resizeWith(done <-chan struct{}, r Resizer, dst, src []byte, width, height, dp, sp int) {
+	if c, ok := r.(cancelResizer); ok {
+		c.resize(done, dst, src, width, height, dp, sp)
+		return
+	}
+	r.Resize(dst, src, width, height, dp, sp)
+}
+
+// scaleRows scales rows in chunks of cancelRows and stops once done is
+// closed
+func scaleRows(done <-chan struct{}, scaler scaler, band bandScaler, pos int, vertical bool,
+	dst, src []byte, cof []int16, cofscale int, off []int16,
+	taps, width, height, dp, sp int) {
+	for y := 0; y < height; y += cancelRows {
+		if canceled(done) {
+			return
+		}
+		rows := min(cancelRows, height-y)
+		next := width
+		if vertical {
+			next = rows
+		}
+		if band != nil {
+			band(dst[:dp*(rows-1)+width], src, pos+y*int(bin(vertical)), width, rows, dp, sp)
+		} else {
+			scaler(dst[:dp*(rows-1)+width], src, cof[:next*taps*cofscale], off[:next],
+				taps, width, rows, dp, sp)
+		}
+		if y+rows == height {
+			return
+		}
+		dst = dst[dp*rows:]
+		if vertical {
+			cof = cof[rows*taps*cofscale:]
+			for _, v := range off[:rows] {
+				src = src[sp*int(v):]
+			}
+			off = off[rows:]
+		} else {
+			src = src[sp*rows:]
+		}
+	}
+}
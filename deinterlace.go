@@ -0,0 +1,173 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+import (
+	"fmt"
+)
+
+// DeinterlaceMode is a deinterlacing algorithm
+// It is used when converting interlaced input to progressive output
+type DeinterlaceMode int
+
+const (
+	// DeinterlaceBob keeps the first field and interpolates missing lines
+	// from the first field only
+	DeinterlaceBob DeinterlaceMode = iota
+	// DeinterlaceBlend blends both fields with a [1 2 1] vertical filter
+	DeinterlaceBlend
+	// DeinterlaceEdge keeps the first field and interpolates missing lines
+	// along the best edge direction
+	DeinterlaceEdge
+)
+
+func (m DeinterlaceMode) String() string {
+	switch m {
+	case DeinterlaceBob:
+		return "bob"
+	case DeinterlaceBlend:
+		return "blend"
+	case DeinterlaceEdge:
+		return "edge"
+	}
+	return fmt.Sprintf("DeinterlaceMode(%d)", int(m))
+}
+
+func checkDeinterlaceMode(mode DeinterlaceMode) error {
+	switch mode {
+	case DeinterlaceBob, DeinterlaceBlend, DeinterlaceEdge:
+		return nil
+	}
+	return fmt.Errorf("invalid deinterlace mode %v", mode)
+}
+
+// deinterlacer converts one interlaced plane into a progressive plane of
+// the same size
+type deinterlacer struct {
+	mode  DeinterlaceMode
+	first int     // first field line, 0 for top & 1 for bottom
+	bob   Resizer // missing lines resizer, only used by bob
+}
+
+// getFieldSizes returns the number of kept & missing lines of a frame
+func getFieldSizes(height, first int) (int, int) {
+	kept := (height + 1 - first) >> 1
+	return kept, height - kept
+}
+
+func newDeinterlacer(mode DeinterlaceMode, order FieldOrder, width, height, pack, threads int, disableAsm bool, pool *Pool, filter Filter) *deinterlacer {
+	d := &deinterlacer{
+		mode:  mode,
+		first: int(bin(order == BottomFieldFirst)),
+	}
+	kept, missing := getFieldSizes(height, d.first)
+	if mode == DeinterlaceBob && kept > 1 {
+		// missing lines sit halfway between kept lines, one half line
+		// below for top fields & one half line above for bottom fields
+		d.bob = NewResize(&ResizerConfig{
+			Depth:      8,
+			Input:      kept,
+			Output:     missing,
+			Vertical:   true,
+			Interlaced: false,
+			Pack:       pack,
+			Threads:    min(threads, missing),
+			DisableAsm: disableAsm,
+			Pool:       pool,
+			shift:      0.5 - float64(d.first),
+		}, filter)
+	}
+	return d
+}
+
+func (d *deinterlacer) Deinterlace(dst, src *Plane, done <-chan struct{}) {
+	width := src.Width * src.Pack
+	switch d.mode {
+	case DeinterlaceBob:
+		bobPlane(done, d.bob, dst, src, d.first)
+	case DeinterlaceBlend:
+		blendPlane(dst.Data, src.Data, width, src.Height, dst.Pitch, src.Pitch)
+	case DeinterlaceEdge:
+		edgePlane(dst.Data, src.Data, width, src.Height, src.Pack, d.first, dst.Pitch, src.Pitch)
+	}
+}
+
+// bobPlane copies lines from the first field and resizes them into the
+// other field, or doubles them when the first field has a single line
+func bobPlane(done <-chan struct{}, bob Resizer, dst, src *Plane, first int) {
+	width := src.Width * src.Pack
+	kept, missing := getFieldSizes(src.Height, first)
+	sd, dd := src.Data[src.Pitch*first:], dst.Data[dst.Pitch*(1-first):]
+	copyPlane(dst.Data[dst.Pitch*first:], sd, width, kept, dst.Pitch*2, src.Pitch*2)
+	if bob == nil {
+		copyPlane(dd, sd, width, missing, dst.Pitch*2, 0)
+		return
+	}
+	resizeWith(done, bob, dd, sd, src.Width, kept, dst.Pitch*2, src.Pitch*2)
+}
+
+func blendPlane(dst, src []byte, width, height, dp, sp int) {
+	di := 0
+	for y := 0; y < height; y++ {
+		prev := src[sp*max(y-1, 0):]
+		next := src[sp*min(y+1, height-1):]
+		cur := src[sp*y:]
+		d := dst[di : di+width]
+		for x := range d {
+			d[x] = byte((int(prev[x]) + 2*int(cur[x]) + int(next[x]) + 2) >> 2)
+		}
+		di += dp
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func absdiff(a, b byte) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// edgeDirections are horizontal offsets in pixels of edge directions checked
+// by edgePlane, nearest first so ties keep the least slanted direction
+var edgeDirections = [...]int{-1, 1, -2, 2}
+
+// edgePlane keeps lines from the first field and rebuilds the other field
+// with an edge-based line average, picking the direction with the smallest
+// difference
+func edgePlane(dst, src []byte, width, height, pack, first, dp, sp int) {
+	for y := first; y < height; y += 2 {
+		copy(dst[dp*y:dp*y+width], src[sp*y:sp*y+width])
+	}
+	for y := 1 - first; y < height; y += 2 {
+		prev := src[sp*abs(y-1) : sp*abs(y-1)+width]
+		next := prev
+		if y+1 < height {
+			next = src[sp*(y+1) : sp*(y+1)+width]
+		}
+		d := dst[dp*y : dp*y+width]
+		for x := range d {
+			best := absdiff(prev[x], next[x])
+			pix := int(prev[x]) + int(next[x])
+			for _, dx := range edgeDirections {
+				a, b := x+dx*pack, x-dx*pack
+				if a < 0 || b < 0 || a >= width || b >= width {
+					continue
+				}
+				if diff := absdiff(prev[a], next[b]); diff < best {
+					best = diff
+					pix = int(prev[a]) + int(next[b])
+				}
+			}
+			d[x] = byte((pix + 1) >> 1)
+		}
+	}
+}
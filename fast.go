@@ -0,0 +1,316 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+// fastKernel resizes rows or columns by 2:1, 4:1 or 1:2 ratios
+// Inner pixels share a constant set of coefficients per phase, so they are
+// resized without coefficient & offset tables. Border pixels, where offsets
+// are clipped, still use the generic kernel.
+// Horizontal phases are in bytes, so packed pixels get one phase per
+// component, vertical phases are in rows.
+type fastKernel struct {
+	period   int     // outputs per phase cycle
+	step     int     // inputs per phase cycle
+	left     int     // first inner output
+	count    int     // number of inner outputs, a multiple of period
+	offset   int     // input offset of the first inner output
+	raw      []int16 // generic coefficients
+	offsets  []int16 // generic offsets
+	phases   []int16 // inner coefficients, taps per phase
+	simdcof  []int16 // inner coefficients prepared for simd
+	simdtaps int     // taps of simdcof
+	simd     scaler  // inner simd scaler, 16 pixels at once
+	taps     int     // generic taps
+	// vertical kernels only
+	coeffs   []int16 // generic coefficients prepared for scaler
+	cofscale int
+	starts   []int   // first input row of every output row
+	scaler   scaler  // generic scaler
+	zero     []int16 // offsets of a single row
+}
+
+// getFastRatio returns the phase period & input step in pixels for supported
+// ratios
+func getFastRatio(cfg *ResizerConfig) (int, int) {
+	if cfg.Vertical && cfg.Interlaced || cfg.shift != 0 {
+		return 0, 0
+	}
+	switch {
+	case cfg.Input == cfg.Output*2:
+		return 1, 2
+	case cfg.Input == cfg.Output*4:
+		return 1, 4
+	case cfg.Input*2 == cfg.Output:
+		return 2, 1
+	}
+	return 0, 0
+}
+
+func equalCoeffs(a, b []int16) bool {
+	for i, v := range a {
+		if v != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// floordiv returns a / b rounded toward negative infinity
+func floordiv(a, b int) int {
+	if a < 0 {
+		return -((b - 1 - a) / b)
+	}
+	return a / b
+}
+
+func makeFastKernel(cfg *ResizerConfig, k *kernel, asm bool) *fastKernel {
+	period, step := getFastRatio(cfg)
+	if period == 0 {
+		return nil
+	}
+	taps := k.size
+	size := cfg.Output
+	offsets := k.offsets[:size]
+	pack := cfg.Pack
+	starts := []int{}
+	if cfg.Vertical {
+		starts = getStarts(offsets)
+		offsets = make([]int16, size)
+		for i, v := range k.offsets[:size] {
+			offsets[i] = int16(starts[i]) + v
+		}
+	} else {
+		period, step, size = period*pack, step*pack, size*pack
+		offsets = k.offsets[:size]
+	}
+	if taps&1 != 0 || size < period*2 {
+		return nil
+	}
+	// all outputs of one cycle must share the same input window
+	mid := size >> 1
+	for mid > 0 && offsets[mid-1] == offsets[mid] {
+		mid--
+	}
+	for i := 1; i < period; i++ {
+		if offsets[mid] != offsets[mid+i] {
+			return nil
+		}
+	}
+	f := &fastKernel{
+		period:  period,
+		step:    step,
+		raw:     k.raw,
+		offsets: k.offsets,
+		phases:  k.raw[mid*taps : (mid+period)*taps],
+		taps:    taps,
+	}
+	regular := func(x int) bool {
+		cycle := floordiv(x-mid, period)
+		phase := x - mid - cycle*period
+		return int(offsets[x]) == int(offsets[mid])+cycle*step &&
+			equalCoeffs(k.raw[x*taps:(x+1)*taps], f.phases[phase*taps:(phase+1)*taps])
+	}
+	left := mid
+	for left > 0 && regular(left-1) {
+		left--
+	}
+	right := mid
+	for right < size && regular(right) {
+		right++
+	}
+	left += (mid - left) % period
+	f.left = left
+	f.count = (right - left) / period * period
+	f.offset = int(offsets[left])
+	if f.count == 0 {
+		return nil
+	}
+	if cfg.Vertical {
+		f.offsets = k.offsets[:size]
+		f.coeffs, f.cofscale = k.coeffs, k.cofscale
+		f.starts = starts
+		f.scaler = getVerticalScaler(taps, asm)
+		f.zero = make([]int16, 1)
+		if asm && hasAsm() {
+			f.simd = f.scaler
+			f.simdcof, _ = prepareVerticalCoeffs(f.phases, period, taps)
+			f.simdtaps = taps
+		}
+		return f
+	}
+	f.simd = getFastScaler(period/pack, step/pack, pack, asm)
+	f.simdtaps = taps / pack
+	if f.simd != nil && f.simdtaps&1 == 0 {
+		f.simdcof = prepareFastCoeffs(getPixelPhases(f.phases, taps, pack), f.simdtaps)
+	} else {
+		f.simd = nil
+	}
+	return f
+}
+
+// getPixelPhases returns coefficients of the first component of every pixel
+// phase from unpacked phases
+func getPixelPhases(phases []int16, taps, pack int) []int16 {
+	if pack == 1 {
+		return phases
+	}
+	dst := []int16{}
+	for i := 0; i < len(phases); i += taps * pack {
+		for j := 0; j < taps; j += pack {
+			dst = append(dst, phases[i+j])
+		}
+	}
+	return dst
+}
+
+// prepareFastCoeffs broadcasts every pair of taps over a simd register
+func prepareFastCoeffs(cof []int16, taps int) []int16 {
+	xwidth := 8
+	dst := make([]int16, 0, len(cof)*xwidth>>1)
+	for i := 0; i < len(cof); i += 2 {
+		for j := 0; j < xwidth; j += 2 {
+			dst = append(dst, cof[i], cof[i+1])
+		}
+	}
+	return dst
+}
+
+// scaleColumns resizes output bytes [x, x+width) of every row, dst starts
+// at byte x & src at the first byte of its rows
+func (f *fastKernel) scaleColumns(dst, src []byte, x, width, height, dp, sp int) {
+	last := x + width
+	left := max(x, min(f.left, last))
+	// inner columns cover whole phase cycles
+	cycle := (left - f.left + f.period - 1) / f.period
+	left = min(f.left+cycle*f.period, last)
+	right := max(left, min(f.left+f.count, last))
+	right = left + (right-left)/f.period*f.period
+	f.scaleBorder(dst, src, x, x, left, height, dp, sp)
+	if count := right - left; count > 0 {
+		inner := dst[left-x:]
+		isrc := src[f.offset+cycle*f.step:]
+		n := 0
+		if f.simd != nil {
+			n = count &^ 15
+			if n > 0 {
+				f.simd(inner, isrc, f.simdcof, nil, f.simdtaps, n, height, dp, sp)
+			}
+		}
+		if n < count {
+			h8periodGo(inner[n:], isrc[n/f.period*f.step:], f.phases,
+				f.taps, count-n, height, dp, sp, f.period, f.step)
+		}
+	}
+	f.scaleBorder(dst, src, x, right, last, height, dp, sp)
+}
+
+// scaleBorder resizes output bytes [first, last) of a tile starting at byte
+// x with the generic kernel
+func (f *fastKernel) scaleBorder(dst, src []byte, x, first, last, height, dp, sp int) {
+	if first == last {
+		return
+	}
+	h8scaleNGo(dst[first-x:], src, f.raw[first*f.taps:], f.offsets[first:last],
+		f.taps, last-first, height, dp, sp)
+}
+
+// scaleVertical resizes output rows [y, y+height) of a band, src starts at
+// the first input row of row y
+func (f *fastKernel) scaleVertical(dst, src []byte, y, width, height, dp, sp int) {
+	last := y + height
+	left := max(y, min(f.left, last))
+	right := max(left, min(f.left+f.count, last))
+	f.scaleRows(dst, src, y, y, left, f.taps, width, dp, sp)
+	f.scaleInner(dst, src, y, left, right, f.taps, width, dp, sp)
+	f.scaleRows(dst, src, y, right, last, f.taps, width, dp, sp)
+}
+
+// scaleInner resizes inner rows [first, last) of a band starting at row y
+// with phase coefficients
+func (f *fastKernel) scaleInner(dst, src []byte, y, first, last, taps, width, dp, sp int) {
+	if first == last {
+		return
+	}
+	cycle := (first - f.left) / f.period
+	phase := first - f.left - cycle*f.period
+	dst = dst[dp*(first-y):]
+	s := src[sp*(f.offset+cycle*f.step-f.starts[y]):]
+	if f.simd != nil {
+		// one simd call per row with the coefficients of its phase
+		n := len(f.simdcof) / f.period
+		for i := 0; i < last-first; i++ {
+			f.simd(dst[dp*i:dp*i+width], s, f.simdcof[phase*n:(phase+1)*n], f.zero,
+				taps, width, 1, dp, sp)
+			if phase++; phase == f.period && i+1 < last-first {
+				phase = 0
+				s = s[sp*f.step:]
+			}
+		}
+		return
+	}
+	v8periodGo(dst, s, f.phases, taps, width, last-first, dp, sp, f.period, f.step, phase)
+}
+
+// scaleRows resizes rows [first, last) of a band starting at row y with the
+// generic scaler
+func (f *fastKernel) scaleRows(dst, src []byte, y, first, last, taps, width, dp, sp int) {
+	if first == last {
+		return
+	}
+	n := last - first
+	f.scaler(dst[dp*(first-y):dp*(last-y-1)+width], src[sp*(f.starts[first]-f.starts[y]):],
+		f.coeffs[first*taps*f.cofscale:last*taps*f.cofscale], f.offsets[first:last],
+		taps, width, n, dp, sp)
+}
+
+// h8periodGo resizes rows with constant coefficients per phase
+func h8periodGo(dst, src []byte, cof []int16,
+	taps, width, height, dp, sp, period, step int) {
+	di := 0
+	si := 0
+	for y := 0; y < height; y++ {
+		s := src[si:]
+		d := dst[di : di+width]
+		for x := 0; x < width; x += period {
+			c := cof
+			for p := range d[x : x+period] {
+				pix := int32(0)
+				for i, v := range s[:taps] {
+					pix += int32(v) * int32(c[i])
+				}
+				d[x+p] = u8(int((pix + 1<<(Bits-1)) >> Bits))
+				c = c[taps:]
+			}
+			s = s[step:]
+		}
+		di += dp
+		si += sp
+	}
+}
+
+// v8periodGo resizes rows with constant coefficients per phase, rows of a
+// cycle read the same input rows, then input moves step rows down
+// phase = phase of the first row
+func v8periodGo(dst, src []byte, cof []int16,
+	taps, width, height, dp, sp, period, step, phase int) {
+	di := 0
+	c := cof[phase*taps:]
+	for y := phase; y < height+phase; y++ {
+		d := dst[di : di+width]
+		for x := range d {
+			pix := int32(0)
+			for i, v := range c[:taps] {
+				pix += int32(src[sp*i+x]) * int32(v)
+			}
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
+		}
+		c = c[taps:]
+		if (y+1)%period == 0 && y+1 < height+phase {
+			c = cof
+			src = src[sp*step:]
+		}
+		di += dp
+	}
+}
@@ -5,6 +5,7 @@
 package rez
 
 import (
+	"fmt"
 	"math"
 )
 
@@ -16,6 +17,22 @@ type Filter interface {
 	Get(dx float64) float64
 }
 
+// FractionalFilter is an optional Filter interface for filters whose
+// support radius is not an integer
+// Taps must return Support rounded up
+type FractionalFilter interface {
+	Filter
+	Support() float64
+}
+
+// getSupport returns the filter support radius in pixels
+func getSupport(filter Filter) float64 {
+	if f, ok := filter.(FractionalFilter); ok {
+		return f.Support()
+	}
+	return float64(filter.Taps())
+}
+
 type bilinear struct{}
 
 func (bilinear) Taps() int    { return 1 }
@@ -79,7 +96,11 @@ type lanczos struct {
 }
 
 func (f lanczos) Taps() int {
-	return int(f.alpha)
+	return int(math.Ceil(f.alpha))
+}
+
+func (f lanczos) Support() float64 {
+	return f.alpha
 }
 
 func (lanczos) Name() string {
@@ -101,3 +122,391 @@ func (f lanczos) Get(x float64) float64 {
 func NewLanczosFilter(alpha int) Filter {
 	return lanczos{alpha: float64(alpha)}
 }
+
+type box struct{}
+
+func (box) Taps() int    { return 1 }
+func (box) Name() string { return "box" }
+
+func (box) Get(x float64) float64 {
+	if x < 0.5 {
+		return 1
+	} else if x == 0.5 {
+		return 0.5
+	}
+	return 0
+}
+
+// NewBoxFilter exports a box filter, which is an area average when
+// downscaling
+func NewBoxFilter() Filter {
+	return box{}
+}
+
+type nearest struct {
+	box
+}
+
+func (nearest) Name() string { return "nearest" }
+
+// pointSampler is implemented by filters which select exactly one input
+// pixel for every output pixel
+type pointSampler interface {
+	pointSample()
+}
+
+func (nearest) pointSample() {}
+
+// NewNearestFilter exports a nearest-neighbor filter
+// It never blends pixels, which is suitable for pixel art and label maps
+func NewNearestFilter() Filter {
+	return nearest{}
+}
+
+type hermite struct{}
+
+func (hermite) Taps() int    { return 1 }
+func (hermite) Name() string { return "hermite" }
+
+func (hermite) Get(x float64) float64 {
+	if x < 1 {
+		return 1 + x*x*(2*x-3)
+	}
+	return 0
+}
+
+// NewHermiteFilter exports a hermite filter
+func NewHermiteFilter() Filter {
+	return hermite{}
+}
+
+type gaussian struct {
+	support float64
+	scale   float64
+}
+
+func (f gaussian) Taps() int {
+	return int(math.Ceil(f.support))
+}
+
+func (f gaussian) Support() float64 {
+	return f.support
+}
+
+func (gaussian) Name() string {
+	return "gaussian"
+}
+
+func (f gaussian) Get(x float64) float64 {
+	if x >= f.support {
+		return 0
+	}
+	return math.Exp(x * x * f.scale)
+}
+
+// NewGaussianFilter exports a gaussian filter where <sigma> is the standard
+// deviation in pixels
+// The filter is truncated at three times <sigma>, output pixels without any
+// input pixel in that range copy the nearest input pixel
+func NewGaussianFilter(sigma float64) Filter {
+	if !(sigma > 0) {
+		panic(fmt.Errorf("invalid sigma %v", sigma))
+	}
+	return gaussian{
+		support: 3 * sigma,
+		scale:   -1 / (2 * sigma * sigma),
+	}
+}
+
+// Window is a window function used by windowed sinc filters
+// Get is called with the distance to the filter center normalized by the
+// filter support, between 0 and 1
+type Window interface {
+	Name() string
+	Get(x float64) float64
+}
+
+type cosineWindow struct {
+	name string
+	a    []float64
+}
+
+func (w cosineWindow) Name() string {
+	return w.name
+}
+
+func (w cosineWindow) Get(x float64) float64 {
+	v := 0.0
+	for i, a := range w.a {
+		v += a * math.Cos(float64(i)*math.Pi*x)
+	}
+	return v
+}
+
+// NewHannWindow exports a hann window
+func NewHannWindow() Window {
+	return cosineWindow{"hann", []float64{0.5, 0.5}}
+}
+
+// NewHammingWindow exports a hamming window
+func NewHammingWindow() Window {
+	return cosineWindow{"hamming", []float64{0.54, 0.46}}
+}
+
+// NewBlackmanWindow exports a blackman window
+func NewBlackmanWindow() Window {
+	return cosineWindow{"blackman", []float64{0.42, 0.5, 0.08}}
+}
+
+// NewBlackmanHarrisWindow exports a 4-term blackman-harris window
+func NewBlackmanHarrisWindow() Window {
+	return cosineWindow{"blackman-harris", []float64{0.35875, 0.48829, 0.14128, 0.01168}}
+}
+
+type kaiserWindow struct {
+	beta float64
+	norm float64
+}
+
+func (kaiserWindow) Name() string {
+	return "kaiser"
+}
+
+func (w kaiserWindow) Get(x float64) float64 {
+	if x >= 1 {
+		return 0
+	}
+	return bessel0(w.beta*math.Sqrt(1-x*x)) / w.norm
+}
+
+// bessel0 returns the modified bessel function of the first kind of order 0
+func bessel0(x float64) float64 {
+	sum := 1.0
+	term := 1.0
+	for k := 1.0; term > sum*1e-12; k++ {
+		term *= x * x / (4 * k * k)
+		sum += term
+	}
+	return sum
+}
+
+// NewKaiserWindow exports a kaiser window where <beta> controls the
+// tradeoff between main lobe width and side lobe level
+func NewKaiserWindow(beta float64) Window {
+	return kaiserWindow{beta: beta, norm: bessel0(beta)}
+}
+
+type windowedSinc struct {
+	window Window
+	lobes  float64
+}
+
+func (f windowedSinc) Taps() int {
+	return int(f.lobes)
+}
+
+func (f windowedSinc) Name() string {
+	return f.window.Name() + "-sinc"
+}
+
+func (f windowedSinc) Get(x float64) float64 {
+	if x >= f.lobes {
+		return 0
+	} else if x == 0 {
+		return 1
+	}
+	b := x * math.Pi
+	return math.Sin(b) / b * f.window.Get(x/f.lobes)
+}
+
+// NewWindowedSincFilter exports a sinc filter with <lobes> lobes on each
+// side, weighted by <window>
+func NewWindowedSincFilter(window Window, lobes int) Filter {
+	return windowedSinc{window: window, lobes: float64(lobes)}
+}
+
+type spline struct {
+	name string
+	cof  [][4]float64 // one cubic polynomial per unit interval
+}
+
+func (f spline) Taps() int {
+	return len(f.cof)
+}
+
+func (f spline) Name() string {
+	return f.name
+}
+
+func (f spline) Get(x float64) float64 {
+	i := int(x)
+	if i >= len(f.cof) {
+		return 0
+	}
+	c := &f.cof[i]
+	x -= float64(i)
+	return c[3] + x*(c[2]+x*(c[1]+x*c[0]))
+}
+
+// NewSpline16Filter exports a 2-lobes spline filter
+func NewSpline16Filter() Filter {
+	return spline{"spline16", [][4]float64{
+		{1, -9.0 / 5, -1.0 / 5, 1},
+		{-1.0 / 3, 4.0 / 5, -7.0 / 15, 0},
+	}}
+}
+
+// NewSpline36Filter exports a 3-lobes spline filter
+func NewSpline36Filter() Filter {
+	return spline{"spline36", [][4]float64{
+		{13.0 / 11, -453.0 / 209, -3.0 / 209, 1},
+		{-6.0 / 11, 270.0 / 209, -156.0 / 209, 0},
+		{1.0 / 11, -45.0 / 209, 26.0 / 209, 0},
+	}}
+}
+
+// NewSpline64Filter exports a 4-lobes spline filter
+func NewSpline64Filter() Filter {
+	return spline{"spline64", [][4]float64{
+		{49.0 / 41, -6387.0 / 2911, -3.0 / 2911, 1},
+		{-24.0 / 41, 4032.0 / 2911, -2328.0 / 2911, 0},
+		{6.0 / 41, -1008.0 / 2911, 582.0 / 2911, 0},
+		{-1.0 / 41, 168.0 / 2911, -97.0 / 2911, 0},
+	}}
+}
+
+type blurred struct {
+	filter  Filter
+	blur    float64
+	support float64
+}
+
+func (f blurred) Taps() int {
+	return int(math.Ceil(f.support))
+}
+
+func (f blurred) Support() float64 {
+	return f.support
+}
+
+func (f blurred) Name() string {
+	return fmt.Sprintf("%v-blur%v", f.filter.Name(), f.blur)
+}
+
+func (f blurred) Get(x float64) float64 {
+	return f.filter.Get(x / f.blur)
+}
+
+type pointBlurred struct {
+	blurred
+}
+
+func (pointBlurred) pointSample() {}
+
+// NewBlurFilter exports <filter> scaled by a <blur> factor
+// A blur above 1 widens the kernel and softens the output, a blur below 1
+// narrows the kernel and sharpens the output
+// Point sampling filters like nearest stay point sampling
+func NewBlurFilter(filter Filter, blur float64) Filter {
+	if blur <= 0 {
+		panic(fmt.Errorf("invalid blur %v", blur))
+	}
+	f := blurred{
+		filter:  filter,
+		blur:    blur,
+		support: getSupport(filter) * blur,
+	}
+	if _, ok := filter.(pointSampler); ok {
+		return pointBlurred{f}
+	}
+	return f
+}
+
+type table struct {
+	name    string
+	samples []float64
+	support float64
+	scale   float64 // samples per pixel
+}
+
+func (f table) Taps() int {
+	return int(math.Ceil(f.support))
+}
+
+func (f table) Support() float64 {
+	return f.support
+}
+
+func (f table) Name() string {
+	return f.name
+}
+
+func (f table) Get(x float64) float64 {
+	if x >= f.support {
+		return 0
+	}
+	pos := x * f.scale
+	i := int(pos)
+	if i+1 >= len(f.samples) {
+		return f.samples[len(f.samples)-1]
+	}
+	a, b := f.samples[i], f.samples[i+1]
+	return a + (b-a)*(pos-float64(i))
+}
+
+func newTable(name string, samples []float64, support float64) table {
+	if len(samples) < 2 {
+		panic(fmt.Errorf("invalid sample count %v", len(samples)))
+	}
+	if support <= 0 {
+		panic(fmt.Errorf("invalid support %v", support))
+	}
+	f := table{
+		name:    name,
+		samples: make([]float64, len(samples)),
+		support: support,
+		scale:   float64(len(samples)-1) / support,
+	}
+	// normalize the symmetric impulse response to a unit area
+	area := 0.0
+	for i := 1; i < len(samples); i++ {
+		area += samples[i-1] + samples[i]
+	}
+	area /= f.scale
+	if area == 0 {
+		area = 1
+	}
+	for i, v := range samples {
+		f.samples[i] = v / area
+	}
+	return f
+}
+
+// NewTableFilter exports a filter from <samples> uniformly spaced between 0
+// and <support> pixels, both included
+// Samples are linearly interpolated and normalized to a unit area
+func NewTableFilter(samples []float64, support float64) Filter {
+	return newTable("table", samples, support)
+}
+
+type pointTable struct {
+	table
+}
+
+func (pointTable) pointSample() {}
+
+// SampleFilter exports a tabulated copy of <filter> using <count> samples
+// Tabulated filters are faster to evaluate when building large kernels
+// Point sampling filters like nearest stay point sampling
+func SampleFilter(filter Filter, count int) Filter {
+	support := getSupport(filter)
+	samples := make([]float64, count)
+	for i := range samples {
+		samples[i] = filter.Get(support * float64(i) / float64(count-1))
+	}
+	f := newTable(filter.Name()+"-table", samples, support)
+	if _, ok := filter.(pointSampler); ok {
+		return pointTable{f}
+	}
+	return f
+}
@@ -15,9 +15,9 @@ func h8scale2Go(dst, src []byte, cof, off []int16,
 		s := src[si:]
 		d := dst[di:]
 		for x, xoff := range off[:width] {
-			pix := int(s[xoff+0])*int(c[0]) +
-				int(s[xoff+1])*int(c[1])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(s[xoff+0])*int32(c[0]) +
+				int32(s[xoff+1])*int32(c[1])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 			c = c[2:]
 		}
 		di += dp
@@ -32,9 +32,9 @@ func v8scale2Go(dst, src []byte, cof, off []int16,
 		src = src[sp*int(yoff):]
 		d := dst[di:]
 		for x := range d[:width] {
-			pix := int(src[sp*0+x])*int(cof[0]) +
-				int(src[sp*1+x])*int(cof[1])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(src[sp*0+x])*int32(cof[0]) +
+				int32(src[sp*1+x])*int32(cof[1])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 		}
 		cof = cof[2:]
 		di += dp
@@ -50,11 +50,11 @@ func h8scale4Go(dst, src []byte, cof, off []int16,
 		s := src[si:]
 		d := dst[di:]
 		for x, xoff := range off[:width] {
-			pix := int(s[xoff+0])*int(c[0]) +
-				int(s[xoff+1])*int(c[1]) +
-				int(s[xoff+2])*int(c[2]) +
-				int(s[xoff+3])*int(c[3])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(s[xoff+0])*int32(c[0]) +
+				int32(s[xoff+1])*int32(c[1]) +
+				int32(s[xoff+2])*int32(c[2]) +
+				int32(s[xoff+3])*int32(c[3])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 			c = c[4:]
 		}
 		di += dp
@@ -69,11 +69,11 @@ func v8scale4Go(dst, src []byte, cof, off []int16,
 		src = src[sp*int(yoff):]
 		d := dst[di:]
 		for x := range d[:width] {
-			pix := int(src[sp*0+x])*int(cof[0]) +
-				int(src[sp*1+x])*int(cof[1]) +
-				int(src[sp*2+x])*int(cof[2]) +
-				int(src[sp*3+x])*int(cof[3])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(src[sp*0+x])*int32(cof[0]) +
+				int32(src[sp*1+x])*int32(cof[1]) +
+				int32(src[sp*2+x])*int32(cof[2]) +
+				int32(src[sp*3+x])*int32(cof[3])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 		}
 		cof = cof[4:]
 		di += dp
@@ -89,13 +89,13 @@ func h8scale6Go(dst, src []byte, cof, off []int16,
 		s := src[si:]
 		d := dst[di:]
 		for x, xoff := range off[:width] {
-			pix := int(s[xoff+0])*int(c[0]) +
-				int(s[xoff+1])*int(c[1]) +
-				int(s[xoff+2])*int(c[2]) +
-				int(s[xoff+3])*int(c[3]) +
-				int(s[xoff+4])*int(c[4]) +
-				int(s[xoff+5])*int(c[5])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(s[xoff+0])*int32(c[0]) +
+				int32(s[xoff+1])*int32(c[1]) +
+				int32(s[xoff+2])*int32(c[2]) +
+				int32(s[xoff+3])*int32(c[3]) +
+				int32(s[xoff+4])*int32(c[4]) +
+				int32(s[xoff+5])*int32(c[5])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 			c = c[6:]
 		}
 		di += dp
@@ -110,13 +110,13 @@ func v8scale6Go(dst, src []byte, cof, off []int16,
 		src = src[sp*int(yoff):]
 		d := dst[di:]
 		for x := range d[:width] {
-			pix := int(src[sp*0+x])*int(cof[0]) +
-				int(src[sp*1+x])*int(cof[1]) +
-				int(src[sp*2+x])*int(cof[2]) +
-				int(src[sp*3+x])*int(cof[3]) +
-				int(src[sp*4+x])*int(cof[4]) +
-				int(src[sp*5+x])*int(cof[5])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(src[sp*0+x])*int32(cof[0]) +
+				int32(src[sp*1+x])*int32(cof[1]) +
+				int32(src[sp*2+x])*int32(cof[2]) +
+				int32(src[sp*3+x])*int32(cof[3]) +
+				int32(src[sp*4+x])*int32(cof[4]) +
+				int32(src[sp*5+x])*int32(cof[5])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 		}
 		cof = cof[6:]
 		di += dp
@@ -132,15 +132,15 @@ func h8scale8Go(dst, src []byte, cof, off []int16,
 		s := src[si:]
 		d := dst[di:]
 		for x, xoff := range off[:width] {
-			pix := int(s[xoff+0])*int(c[0]) +
-				int(s[xoff+1])*int(c[1]) +
-				int(s[xoff+2])*int(c[2]) +
-				int(s[xoff+3])*int(c[3]) +
-				int(s[xoff+4])*int(c[4]) +
-				int(s[xoff+5])*int(c[5]) +
-				int(s[xoff+6])*int(c[6]) +
-				int(s[xoff+7])*int(c[7])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(s[xoff+0])*int32(c[0]) +
+				int32(s[xoff+1])*int32(c[1]) +
+				int32(s[xoff+2])*int32(c[2]) +
+				int32(s[xoff+3])*int32(c[3]) +
+				int32(s[xoff+4])*int32(c[4]) +
+				int32(s[xoff+5])*int32(c[5]) +
+				int32(s[xoff+6])*int32(c[6]) +
+				int32(s[xoff+7])*int32(c[7])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 			c = c[8:]
 		}
 		di += dp
@@ -155,15 +155,15 @@ func v8scale8Go(dst, src []byte, cof, off []int16,
 		src = src[sp*int(yoff):]
 		d := dst[di:]
 		for x := range d[:width] {
-			pix := int(src[sp*0+x])*int(cof[0]) +
-				int(src[sp*1+x])*int(cof[1]) +
-				int(src[sp*2+x])*int(cof[2]) +
-				int(src[sp*3+x])*int(cof[3]) +
-				int(src[sp*4+x])*int(cof[4]) +
-				int(src[sp*5+x])*int(cof[5]) +
-				int(src[sp*6+x])*int(cof[6]) +
-				int(src[sp*7+x])*int(cof[7])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(src[sp*0+x])*int32(cof[0]) +
+				int32(src[sp*1+x])*int32(cof[1]) +
+				int32(src[sp*2+x])*int32(cof[2]) +
+				int32(src[sp*3+x])*int32(cof[3]) +
+				int32(src[sp*4+x])*int32(cof[4]) +
+				int32(src[sp*5+x])*int32(cof[5]) +
+				int32(src[sp*6+x])*int32(cof[6]) +
+				int32(src[sp*7+x])*int32(cof[7])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 		}
 		cof = cof[8:]
 		di += dp
@@ -179,17 +179,17 @@ func h8scale10Go(dst, src []byte, cof, off []int16,
 		s := src[si:]
 		d := dst[di:]
 		for x, xoff := range off[:width] {
-			pix := int(s[xoff+0])*int(c[0]) +
-				int(s[xoff+1])*int(c[1]) +
-				int(s[xoff+2])*int(c[2]) +
-				int(s[xoff+3])*int(c[3]) +
-				int(s[xoff+4])*int(c[4]) +
-				int(s[xoff+5])*int(c[5]) +
-				int(s[xoff+6])*int(c[6]) +
-				int(s[xoff+7])*int(c[7]) +
-				int(s[xoff+8])*int(c[8]) +
-				int(s[xoff+9])*int(c[9])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(s[xoff+0])*int32(c[0]) +
+				int32(s[xoff+1])*int32(c[1]) +
+				int32(s[xoff+2])*int32(c[2]) +
+				int32(s[xoff+3])*int32(c[3]) +
+				int32(s[xoff+4])*int32(c[4]) +
+				int32(s[xoff+5])*int32(c[5]) +
+				int32(s[xoff+6])*int32(c[6]) +
+				int32(s[xoff+7])*int32(c[7]) +
+				int32(s[xoff+8])*int32(c[8]) +
+				int32(s[xoff+9])*int32(c[9])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 			c = c[10:]
 		}
 		di += dp
@@ -204,17 +204,17 @@ func v8scale10Go(dst, src []byte, cof, off []int16,
 		src = src[sp*int(yoff):]
 		d := dst[di:]
 		for x := range d[:width] {
-			pix := int(src[sp*0+x])*int(cof[0]) +
-				int(src[sp*1+x])*int(cof[1]) +
-				int(src[sp*2+x])*int(cof[2]) +
-				int(src[sp*3+x])*int(cof[3]) +
-				int(src[sp*4+x])*int(cof[4]) +
-				int(src[sp*5+x])*int(cof[5]) +
-				int(src[sp*6+x])*int(cof[6]) +
-				int(src[sp*7+x])*int(cof[7]) +
-				int(src[sp*8+x])*int(cof[8]) +
-				int(src[sp*9+x])*int(cof[9])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(src[sp*0+x])*int32(cof[0]) +
+				int32(src[sp*1+x])*int32(cof[1]) +
+				int32(src[sp*2+x])*int32(cof[2]) +
+				int32(src[sp*3+x])*int32(cof[3]) +
+				int32(src[sp*4+x])*int32(cof[4]) +
+				int32(src[sp*5+x])*int32(cof[5]) +
+				int32(src[sp*6+x])*int32(cof[6]) +
+				int32(src[sp*7+x])*int32(cof[7]) +
+				int32(src[sp*8+x])*int32(cof[8]) +
+				int32(src[sp*9+x])*int32(cof[9])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 		}
 		cof = cof[10:]
 		di += dp
@@ -230,19 +230,19 @@ func h8scale12Go(dst, src []byte, cof, off []int16,
 		s := src[si:]
 		d := dst[di:]
 		for x, xoff := range off[:width] {
-			pix := int(s[xoff+0])*int(c[0]) +
-				int(s[xoff+1])*int(c[1]) +
-				int(s[xoff+2])*int(c[2]) +
-				int(s[xoff+3])*int(c[3]) +
-				int(s[xoff+4])*int(c[4]) +
-				int(s[xoff+5])*int(c[5]) +
-				int(s[xoff+6])*int(c[6]) +
-				int(s[xoff+7])*int(c[7]) +
-				int(s[xoff+8])*int(c[8]) +
-				int(s[xoff+9])*int(c[9]) +
-				int(s[xoff+10])*int(c[10]) +
-				int(s[xoff+11])*int(c[11])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(s[xoff+0])*int32(c[0]) +
+				int32(s[xoff+1])*int32(c[1]) +
+				int32(s[xoff+2])*int32(c[2]) +
+				int32(s[xoff+3])*int32(c[3]) +
+				int32(s[xoff+4])*int32(c[4]) +
+				int32(s[xoff+5])*int32(c[5]) +
+				int32(s[xoff+6])*int32(c[6]) +
+				int32(s[xoff+7])*int32(c[7]) +
+				int32(s[xoff+8])*int32(c[8]) +
+				int32(s[xoff+9])*int32(c[9]) +
+				int32(s[xoff+10])*int32(c[10]) +
+				int32(s[xoff+11])*int32(c[11])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 			c = c[12:]
 		}
 		di += dp
@@ -257,19 +257,19 @@ func v8scale12Go(dst, src []byte, cof, off []int16,
 		src = src[sp*int(yoff):]
 		d := dst[di:]
 		for x := range d[:width] {
-			pix := int(src[sp*0+x])*int(cof[0]) +
-				int(src[sp*1+x])*int(cof[1]) +
-				int(src[sp*2+x])*int(cof[2]) +
-				int(src[sp*3+x])*int(cof[3]) +
-				int(src[sp*4+x])*int(cof[4]) +
-				int(src[sp*5+x])*int(cof[5]) +
-				int(src[sp*6+x])*int(cof[6]) +
-				int(src[sp*7+x])*int(cof[7]) +
-				int(src[sp*8+x])*int(cof[8]) +
-				int(src[sp*9+x])*int(cof[9]) +
-				int(src[sp*10+x])*int(cof[10]) +
-				int(src[sp*11+x])*int(cof[11])
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			pix := int32(src[sp*0+x])*int32(cof[0]) +
+				int32(src[sp*1+x])*int32(cof[1]) +
+				int32(src[sp*2+x])*int32(cof[2]) +
+				int32(src[sp*3+x])*int32(cof[3]) +
+				int32(src[sp*4+x])*int32(cof[4]) +
+				int32(src[sp*5+x])*int32(cof[5]) +
+				int32(src[sp*6+x])*int32(cof[6]) +
+				int32(src[sp*7+x])*int32(cof[7]) +
+				int32(src[sp*8+x])*int32(cof[8]) +
+				int32(src[sp*9+x])*int32(cof[9]) +
+				int32(src[sp*10+x])*int32(cof[10]) +
+				int32(src[sp*11+x])*int32(cof[11])
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 		}
 		cof = cof[12:]
 		di += dp
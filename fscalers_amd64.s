@@ -0,0 +1,481 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// This file is auto-generated - do not modify
+
+DATA	hbits_0<>+0x00(SB)/8, $0x0000200000002000
+DATA	hbits_0<>+0x08(SB)/8, $0x0000200000002000
+GLOBL	hbits_0<>(SB), 8, $16
+
+TEXT ·h8down2Amd64(SB),4,$0-136
+		MOVQ	width+104(FP), CX
+		SHRQ	$4, CX
+		ORQ	CX, CX
+		JE	end_0
+		MOVQ	taps+96(FP), BX
+		SHRQ	$1, BX
+		MOVQ	BX, R12
+		SHLQ	$4, R12
+		MOVQ	src+24(FP), R9
+		MOVQ	dst+0(FP), R10
+		MOVQ	cof+48(FP), R13
+		PXOR	X15, X15
+		MOVO	hbits_0<>(SB), X14
+yloop_1:
+		MOVQ	R9, SI
+		MOVQ	R10, DI
+		MOVQ	CX, R8
+xloop_2:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	BX, DX
+		MOVQ	SI, AX
+		MOVQ	R13, R11
+pairs_3:
+		MOVOU	(R11), X8
+		MOVQ	(AX), X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X0
+		MOVQ	8(AX), X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X1
+		MOVQ	16(AX), X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X2
+		MOVQ	24(AX), X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X3
+		ADDQ	$2, AX
+		ADDQ	$16, R11
+		SUBQ	$1, DX
+		JNE	pairs_3
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$32, SI
+		ADDQ	$16, DI
+		SUBQ	$1, R8
+		JNE	xloop_2
+		ADDQ	sp+128(FP), R9
+		ADDQ	dp+120(FP), R10
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_1
+end_0:
+		RET
+
+TEXT ·h8down4Amd64(SB),4,$0-136
+		MOVQ	width+104(FP), CX
+		SHRQ	$4, CX
+		ORQ	CX, CX
+		JE	end_4
+		MOVQ	taps+96(FP), BX
+		SHRQ	$1, BX
+		MOVQ	BX, R12
+		SHLQ	$4, R12
+		MOVQ	src+24(FP), R9
+		MOVQ	dst+0(FP), R10
+		MOVQ	cof+48(FP), R13
+		PXOR	X15, X15
+		MOVO	hbits_0<>(SB), X14
+yloop_5:
+		MOVQ	R9, SI
+		MOVQ	R10, DI
+		MOVQ	CX, R8
+xloop_6:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	BX, DX
+		MOVQ	SI, AX
+		MOVQ	R13, R11
+pairs_7:
+		MOVOU	(R11), X8
+		MOVQ	(AX), X4
+		MOVQ	6(AX), X5
+		PUNPCKLBW	X15, X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	X8, X4
+		PMADDWL	X8, X5
+		SHUFPS	$216, X5, X4
+		PADDL	X4, X0
+		MOVQ	16(AX), X4
+		MOVQ	22(AX), X5
+		PUNPCKLBW	X15, X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	X8, X4
+		PMADDWL	X8, X5
+		SHUFPS	$216, X5, X4
+		PADDL	X4, X1
+		MOVQ	32(AX), X4
+		MOVQ	38(AX), X5
+		PUNPCKLBW	X15, X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	X8, X4
+		PMADDWL	X8, X5
+		SHUFPS	$216, X5, X4
+		PADDL	X4, X2
+		MOVQ	48(AX), X4
+		MOVQ	54(AX), X5
+		PUNPCKLBW	X15, X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	X8, X4
+		PMADDWL	X8, X5
+		SHUFPS	$216, X5, X4
+		PADDL	X4, X3
+		ADDQ	$2, AX
+		ADDQ	$16, R11
+		SUBQ	$1, DX
+		JNE	pairs_7
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$64, SI
+		ADDQ	$16, DI
+		SUBQ	$1, R8
+		JNE	xloop_6
+		ADDQ	sp+128(FP), R9
+		ADDQ	dp+120(FP), R10
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_5
+end_4:
+		RET
+
+TEXT ·h8up2Amd64(SB),4,$0-136
+		MOVQ	width+104(FP), CX
+		SHRQ	$4, CX
+		ORQ	CX, CX
+		JE	end_8
+		MOVQ	taps+96(FP), BX
+		SHRQ	$1, BX
+		MOVQ	BX, R12
+		SHLQ	$4, R12
+		MOVQ	src+24(FP), R9
+		MOVQ	dst+0(FP), R10
+		MOVQ	cof+48(FP), R13
+		PXOR	X15, X15
+		MOVO	hbits_0<>(SB), X14
+yloop_9:
+		MOVQ	R9, SI
+		MOVQ	R10, DI
+		MOVQ	CX, R8
+xloop_10:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	BX, DX
+		MOVQ	SI, AX
+		MOVQ	R13, R11
+pairs_11:
+		MOVOU	(R11), X8
+		MOVOU	(R11)(R12*1), X9
+		MOVL	(AX), X4
+		MOVL	1(AX), X5
+		PUNPCKLBW	X15, X4
+		PUNPCKLBW	X15, X5
+		PUNPCKLWL	X5, X4
+		MOVO	X4, X6
+		PMADDWL	X8, X4
+		PMADDWL	X9, X6
+		PADDL	X4, X0
+		PADDL	X6, X1
+		MOVL	4(AX), X4
+		MOVL	5(AX), X5
+		PUNPCKLBW	X15, X4
+		PUNPCKLBW	X15, X5
+		PUNPCKLWL	X5, X4
+		MOVO	X4, X6
+		PMADDWL	X8, X4
+		PMADDWL	X9, X6
+		PADDL	X4, X2
+		PADDL	X6, X3
+		ADDQ	$2, AX
+		ADDQ	$16, R11
+		SUBQ	$1, DX
+		JNE	pairs_11
+		MOVO	X0, X4
+		MOVO	X2, X6
+		PUNPCKLLQ	X1, X0
+		PUNPCKHLQ	X1, X4
+		PUNPCKLLQ	X3, X2
+		PUNPCKHLQ	X3, X6
+		PADDL	X14, X0
+		PADDL	X14, X4
+		PADDL	X14, X2
+		PADDL	X14, X6
+		PSRAL	$14, X0
+		PSRAL	$14, X4
+		PSRAL	$14, X2
+		PSRAL	$14, X6
+		PACKSSLW	X4, X0
+		PACKSSLW	X6, X2
+		PACKUSWB	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$8, SI
+		ADDQ	$16, DI
+		SUBQ	$1, R8
+		JNE	xloop_10
+		ADDQ	sp+128(FP), R9
+		ADDQ	dp+120(FP), R10
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_9
+end_8:
+		RET
+
+TEXT ·h8down2Pack4Amd64(SB),4,$0-136
+		MOVQ	width+104(FP), CX
+		SHRQ	$4, CX
+		ORQ	CX, CX
+		JE	end_12
+		MOVQ	taps+96(FP), BX
+		SHRQ	$1, BX
+		MOVQ	BX, R12
+		SHLQ	$4, R12
+		MOVQ	src+24(FP), R9
+		MOVQ	dst+0(FP), R10
+		MOVQ	cof+48(FP), R13
+		PXOR	X15, X15
+		MOVO	hbits_0<>(SB), X14
+yloop_13:
+		MOVQ	R9, SI
+		MOVQ	R10, DI
+		MOVQ	CX, R8
+xloop_14:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	BX, DX
+		MOVQ	SI, AX
+		MOVQ	R13, R11
+pairs_15:
+		MOVOU	(R11), X8
+		MOVL	(AX), X4
+		MOVL	4(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X0
+		MOVL	8(AX), X4
+		MOVL	12(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X1
+		MOVL	16(AX), X4
+		MOVL	20(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X2
+		MOVL	24(AX), X4
+		MOVL	28(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X3
+		ADDQ	$8, AX
+		ADDQ	$16, R11
+		SUBQ	$1, DX
+		JNE	pairs_15
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$32, SI
+		ADDQ	$16, DI
+		SUBQ	$1, R8
+		JNE	xloop_14
+		ADDQ	sp+128(FP), R9
+		ADDQ	dp+120(FP), R10
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_13
+end_12:
+		RET
+
+TEXT ·h8down4Pack4Amd64(SB),4,$0-136
+		MOVQ	width+104(FP), CX
+		SHRQ	$4, CX
+		ORQ	CX, CX
+		JE	end_16
+		MOVQ	taps+96(FP), BX
+		SHRQ	$1, BX
+		MOVQ	BX, R12
+		SHLQ	$4, R12
+		MOVQ	src+24(FP), R9
+		MOVQ	dst+0(FP), R10
+		MOVQ	cof+48(FP), R13
+		PXOR	X15, X15
+		MOVO	hbits_0<>(SB), X14
+yloop_17:
+		MOVQ	R9, SI
+		MOVQ	R10, DI
+		MOVQ	CX, R8
+xloop_18:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	BX, DX
+		MOVQ	SI, AX
+		MOVQ	R13, R11
+pairs_19:
+		MOVOU	(R11), X8
+		MOVL	(AX), X4
+		MOVL	4(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X0
+		MOVL	16(AX), X4
+		MOVL	20(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X1
+		MOVL	32(AX), X4
+		MOVL	36(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X2
+		MOVL	48(AX), X4
+		MOVL	52(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		PMADDWL	X8, X4
+		PADDL	X4, X3
+		ADDQ	$8, AX
+		ADDQ	$16, R11
+		SUBQ	$1, DX
+		JNE	pairs_19
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$64, SI
+		ADDQ	$16, DI
+		SUBQ	$1, R8
+		JNE	xloop_18
+		ADDQ	sp+128(FP), R9
+		ADDQ	dp+120(FP), R10
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_17
+end_16:
+		RET
+
+TEXT ·h8up2Pack4Amd64(SB),4,$0-136
+		MOVQ	width+104(FP), CX
+		SHRQ	$4, CX
+		ORQ	CX, CX
+		JE	end_20
+		MOVQ	taps+96(FP), BX
+		SHRQ	$1, BX
+		MOVQ	BX, R12
+		SHLQ	$4, R12
+		MOVQ	src+24(FP), R9
+		MOVQ	dst+0(FP), R10
+		MOVQ	cof+48(FP), R13
+		PXOR	X15, X15
+		MOVO	hbits_0<>(SB), X14
+yloop_21:
+		MOVQ	R9, SI
+		MOVQ	R10, DI
+		MOVQ	CX, R8
+xloop_22:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	BX, DX
+		MOVQ	SI, AX
+		MOVQ	R13, R11
+pairs_23:
+		MOVOU	(R11), X8
+		MOVOU	(R11)(R12*1), X9
+		MOVL	(AX), X4
+		MOVL	4(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		MOVO	X4, X6
+		PMADDWL	X8, X4
+		PMADDWL	X9, X6
+		PADDL	X4, X0
+		PADDL	X6, X1
+		MOVL	4(AX), X4
+		MOVL	8(AX), X5
+		PUNPCKLBW	X5, X4
+		PUNPCKLBW	X15, X4
+		MOVO	X4, X6
+		PMADDWL	X8, X4
+		PMADDWL	X9, X6
+		PADDL	X4, X2
+		PADDL	X6, X3
+		ADDQ	$8, AX
+		ADDQ	$16, R11
+		SUBQ	$1, DX
+		JNE	pairs_23
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$8, SI
+		ADDQ	$16, DI
+		SUBQ	$1, R8
+		JNE	xloop_22
+		ADDQ	sp+128(FP), R9
+		ADDQ	dp+120(FP), R10
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_21
+end_20:
+		RET
@@ -0,0 +1,170 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package rez
+
+import (
+	"testing"
+)
+
+const (
+	// guard is the number of sentinel bytes around fuzzed planes
+	guard = 64
+	// sentinel is the value of bytes scalers must never write
+	sentinel = 0xA5
+)
+
+func getFuzzFilter(idx, lobes uint8) Filter {
+	switch idx % 6 {
+	case 0:
+		return NewBilinearFilter()
+	case 1:
+		return NewBicubicFilter()
+	case 2:
+		return NewBoxFilter()
+	case 3:
+		return NewSpline36Filter()
+	case 4:
+		return NewGaussianFilter(0.5 + float64(lobes%8)/2)
+	}
+	return NewLanczosFilter(1 + int(lobes%8))
+}
+
+// fuzzPlane returns a plane surrounded by guard sentinel bytes
+func fuzzPlane(width, height, pitch int) (*Plane, []byte) {
+	size := pitch*(height-1) + width
+	buf := make([]byte, guard+pitch*height+guard)
+	for i := range buf {
+		buf[i] = sentinel
+	}
+	return &Plane{
+		Width:  width,
+		Height: height,
+		Pitch:  pitch,
+		Pack:   1,
+		Data:   buf[guard : guard+size],
+	}, buf
+}
+
+// checkGuards fails if any byte outside p valid region was written
+func checkGuards(t *testing.T, name string, p *Plane, buf []byte) {
+	for i, v := range buf {
+		j := i - guard
+		if j >= 0 && j < p.Pitch*p.Height && j%p.Pitch < p.Width {
+			continue
+		}
+		if v != sentinel {
+			t.Fatalf("%v scaler wrote out of bounds at offset %v, row %v col %v",
+				name, j, j/p.Pitch, j%p.Pitch)
+		}
+	}
+}
+
+type fuzzScale struct {
+	vertical bool
+	win      int // input size along the scaled dimension
+	wout     int // output size along the scaled dimension
+	other    int // plane size along the other dimension
+	pack     int
+	dpad     int // extra bytes per dst row
+	spad     int // extra bytes per src row
+	filter   Filter
+	pixels   []byte
+}
+
+// run scales a random plane with asm & go scalers and compares them
+func (s *fuzzScale) run(t *testing.T) {
+	cfg := ResizerConfig{
+		Depth:    8,
+		Input:    s.win,
+		Output:   s.wout,
+		Vertical: s.vertical,
+		Pack:     s.pack,
+		Threads:  1,
+	}
+	ref := cfg
+	ref.DisableAsm = true
+	ka := makeKernel(&cfg, s.filter, 0)
+	kg := makeKernel(&ref, s.filter, 0)
+	sa := getHorizontalScaler(ka.size, true)
+	sg := getHorizontalScaler(kg.size, false)
+	sw, sh, dw, dh := s.win*s.pack, s.other, s.wout*s.pack, s.other
+	if s.vertical {
+		sa = getVerticalScaler(ka.size, true)
+		sg = getVerticalScaler(kg.size, false)
+		sw, sh, dw, dh = s.other*s.pack, s.win, s.other*s.pack, s.wout
+	}
+	if fast := makeFastKernel(&cfg, &ka, true); fast != nil {
+		sa = func(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int) {
+			if s.vertical {
+				fast.scaleVertical(dst, src, 0, width, height, dp, sp)
+				return
+			}
+			// two column tiles check tile positions
+			x := width / 2 &^ (columnAlign - 1)
+			fast.scaleColumns(dst, src, 0, x, height, dp, sp)
+			fast.scaleColumns(dst[x:], src, x, width-x, height, dp, sp)
+		}
+	}
+	src, _ := fuzzPlane(sw, sh, sw+s.spad)
+	for i := range src.Data {
+		src.Data[i] = byte(i)
+		if len(s.pixels) > 0 {
+			src.Data[i] = s.pixels[i%len(s.pixels)]
+		}
+	}
+	pa, ba := fuzzPlane(dw, dh, dw+s.dpad)
+	pg, bg := fuzzPlane(dw, dh, dw+s.dpad)
+	width := dw
+	if s.vertical {
+		width = sw
+	}
+	sa(pa.Data, src.Data, ka.coeffs, ka.offsets, ka.size, width, dh, pa.Pitch, src.Pitch)
+	sg(pg.Data, src.Data, kg.coeffs, kg.offsets, kg.size, width, dh, pg.Pitch, src.Pitch)
+	checkGuards(t, "asm", pa, ba)
+	checkGuards(t, "go", pg, bg)
+	if err := comparePlanes(0, pa, pg); err != nil {
+		t.Fatalf("%+v taps %v: %v", *s, ka.size, err)
+	}
+}
+
+func fuzzScaler(f *testing.F, vertical bool) {
+	// seeds map to sizes & packs as in the fuzz function below
+	f.Add(uint16(62), uint16(30), uint8(20), uint8(0), uint8(0), uint8(0), uint8(0), uint8(0), []byte{0, 255})
+	f.Add(uint16(126), uint16(30), uint8(15), uint8(0), uint8(5), uint8(3), uint8(0), uint8(0), []byte{255, 0, 7})
+	f.Add(uint16(30), uint16(62), uint8(16), uint8(1), uint8(0), uint8(1), uint8(0), uint8(0), []byte{9, 250})
+	f.Add(uint16(37), uint16(100), uint8(5), uint8(3), uint8(3), uint8(7), uint8(5), uint8(2), []byte{17, 255, 0})
+	f.Add(uint16(200), uint16(17), uint8(15), uint8(2), uint8(1), uint8(0), uint8(1), uint8(0), []byte{128})
+	f.Add(uint16(31), uint16(31), uint8(9), uint8(1), uint8(0), uint8(16), uint8(4), uint8(5), []byte{1, 2, 3})
+	f.Add(uint16(500), uint16(16), uint8(16), uint8(0), uint8(9), uint8(1), uint8(5), uint8(7), []byte{255, 0, 0})
+	f.Add(uint16(16), uint16(32), uint8(3), uint8(3), uint8(0), uint8(0), uint8(3), uint8(0), []byte{})
+	f.Fuzz(func(t *testing.T, win, wout uint16, other, pack, dpad, spad, filter, lobes uint8, pixels []byte) {
+		if !hasAsm() {
+			t.Skip("no asm scalers")
+		}
+		s := fuzzScale{
+			vertical: vertical,
+			win:      2 + int(win%511),
+			wout:     2 + int(wout%511),
+			other:    1 + int(other%32),
+			pack:     1 + int(pack%4),
+			dpad:     int(dpad % 32),
+			spad:     int(spad % 32),
+			filter:   getFuzzFilter(filter, lobes),
+			pixels:   pixels,
+		}
+		s.run(t)
+	})
+}
+
+func FuzzHorizontalScaler(f *testing.F) {
+	fuzzScaler(f, false)
+}
+
+func FuzzVerticalScaler(f *testing.F) {
+	fuzzScaler(f, true)
+}
@@ -4,17 +4,11 @@
 
 // This file is auto-generated - do not modify
 
-DATA	zero_0<>+0x00(SB)/8, $0x0000000000000000
-DATA	zero_0<>+0x08(SB)/8, $0x0000000000000000
-GLOBL	zero_0<>(SB), 8, $16
-DATA	hbits_1<>+0x00(SB)/8, $0x0000200000002000
-DATA	hbits_1<>+0x08(SB)/8, $0x0000200000002000
-GLOBL	hbits_1<>(SB), 8, $16
-DATA	u8max_2<>+0x00(SB)/8, $0x00000000000000FF
-DATA	u8max_2<>+0x08(SB)/8, $0x00000000000000FF
-GLOBL	u8max_2<>(SB), 8, $16
+DATA	hbits_0<>+0x00(SB)/8, $0x0000200000002000
+DATA	hbits_0<>+0x08(SB)/8, $0x0000200000002000
+GLOBL	hbits_0<>(SB), 8, $16
 
-TEXT ·h8scale2Amd64(SB),4,$40-136
+TEXT ·h8scale2Amd64(SB),4,$32-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -23,13 +17,13 @@ TEXT ·h8scale2Amd64(SB),4,$40-136
 		ANDQ	$15, DX
 		MOVQ	BX, dstoff+-32(SP)
 		MOVQ	CX, simdroll+-8(SP)
-		MOVQ	DX, asmroll+-16(SP)
+		MOVQ	DX, tail+-16(SP)
 		MOVQ	src+24(FP), AX
 		MOVQ	AX, srcref+-24(SP)
 		MOVQ	taps+96(FP), DX
 		SUBQ	$2, DX
 		PXOR	X15, X15
-		MOVO	hbits_1<>(SB), X14
+		MOVO	hbits_0<>(SB), X14
 		MOVQ	src+24(FP), SI
 		MOVQ	dst+0(FP), DI
 yloop_0:
@@ -37,7 +31,7 @@ yloop_0:
 		MOVQ	cof+48(FP), BP
 		MOVQ	simdroll+-8(SP), CX
 		ORQ	CX, CX
-		JE	nosimdloop_3
+		JE	nosimdloop_2
 simdloop_1:
 		MOVWQSX	(BX), R8
 		MOVWQSX	2(BX), R9
@@ -96,33 +90,90 @@ simdloop_1:
 		ADDQ	$16, DI
 		SUBQ	$1, CX
 		JNE	simdloop_1
-nosimdloop_3:
-		MOVQ	asmroll+-16(SP), CX
+nosimdloop_2:
+		MOVQ	tail+-16(SP), CX
 		ORQ	CX, CX
-		JE	end_4
-asmloop_2:
-		MOVWQSX	(BX), DX
-		MOVBQZX	(SI)(DX*1), AX
-		MOVWQSX	(BP), DX
-		IMULQ	DX
-		MOVQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	1(SI)(DX*1), AX
-		MOVWQSX	2(BP), DX
-		IMULQ	DX
-		ADDQ	$4, BP
-		ADDQ	sum+-40(SP), AX
-		ADDQ	$8192, AX
-		CMOVQLT	zero_0<>(SB), AX
-		SHRQ	$14, AX
-		CMPQ	u8max_2<>(SB), AX
-		CMOVQLT	u8max_2<>(SB), AX
-		ADDQ	$2, BX
-		MOVB	AL, (DI)
+		JE	end_3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X0
+		PINSRW	$1, (SI)(R9*1), X0
+		PINSRW	$2, (SI)(R10*1), X0
+		PINSRW	$3, (SI)(R11*1), X0
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X1
+		PINSRW	$1, (SI)(R9*1), X1
+		PINSRW	$2, (SI)(R10*1), X1
+		PINSRW	$3, (SI)(R11*1), X1
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X2
+		PINSRW	$1, (SI)(R9*1), X2
+		PINSRW	$2, (SI)(R10*1), X2
+		PINSRW	$3, (SI)(R11*1), X2
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X3
+		PINSRW	$1, (SI)(R9*1), X3
+		PINSRW	$2, (SI)(R10*1), X3
+		PINSRW	$3, (SI)(R11*1), X3
+		ADDQ	$32, BX
+		PUNPCKLBW	X15, X0
+		PMADDWL	(BP), X0
+		PUNPCKLBW	X15, X1
+		PMADDWL	16(BP), X1
+		PUNPCKLBW	X15, X2
+		PMADDWL	32(BP), X2
+		PUNPCKLBW	X15, X3
+		PMADDWL	48(BP), X3
+		ADDQ	$64, BP
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, tail+-16(SP)
+		JE	skip8_4
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_4:
+		TESTQ	$4, tail+-16(SP)
+		JE	skip4_5
+		MOVQ	X0, AX
+		MOVL	AX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_5:
+		TESTQ	$2, tail+-16(SP)
+		JE	skip2_6
+		MOVQ	X0, AX
+		MOVW	AX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_6:
+		TESTQ	$1, tail+-16(SP)
+		JE	skip1_7
+		MOVQ	X0, AX
+		MOVB	AX, (DI)
 		ADDQ	$1, DI
-		SUBQ	$1, CX
-		JNE	asmloop_2
-end_4:
+skip1_7:
+end_3:
 		MOVQ	srcref+-24(SP), SI
 		ADDQ	dstoff+-32(SP), DI
 		ADDQ	sp+128(FP), SI
@@ -131,7 +182,7 @@ end_4:
 		JNE	yloop_0
 		RET
 
-TEXT ·h8scale4Amd64(SB),4,$40-136
+TEXT ·h8scale4Amd64(SB),4,$32-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -140,22 +191,22 @@ TEXT ·h8scale4Amd64(SB),4,$40-136
 		ANDQ	$15, DX
 		MOVQ	BX, dstoff+-32(SP)
 		MOVQ	CX, simdroll+-8(SP)
-		MOVQ	DX, asmroll+-16(SP)
+		MOVQ	DX, tail+-16(SP)
 		MOVQ	src+24(FP), AX
 		MOVQ	AX, srcref+-24(SP)
 		MOVQ	taps+96(FP), DX
 		SUBQ	$2, DX
 		PXOR	X15, X15
-		MOVO	hbits_1<>(SB), X14
+		MOVO	hbits_0<>(SB), X14
 		MOVQ	src+24(FP), SI
 		MOVQ	dst+0(FP), DI
-yloop_5:
+yloop_8:
 		MOVQ	off+72(FP), BX
 		MOVQ	cof+48(FP), BP
 		MOVQ	simdroll+-8(SP), CX
 		ORQ	CX, CX
-		JE	nosimdloop_8
-simdloop_6:
+		JE	nosimdloop_10
+simdloop_9:
 		MOVWQSX	(BX), AX
 		MOVWQSX	2(BX), DX
 		MOVL	(SI)(AX*1), X0
@@ -244,53 +295,132 @@ simdloop_6:
 		MOVOU	X0, (DI)
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	simdloop_6
-nosimdloop_8:
-		MOVQ	asmroll+-16(SP), CX
+		JNE	simdloop_9
+nosimdloop_10:
+		MOVQ	tail+-16(SP), CX
 		ORQ	CX, CX
-		JE	end_9
-asmloop_7:
-		MOVWQSX	(BX), DX
-		MOVBQZX	(SI)(DX*1), AX
-		MOVWQSX	(BP), DX
-		IMULQ	DX
-		MOVQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	1(SI)(DX*1), AX
-		MOVWQSX	2(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	2(SI)(DX*1), AX
-		MOVWQSX	4(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	3(SI)(DX*1), AX
-		MOVWQSX	6(BP), DX
-		IMULQ	DX
-		ADDQ	$8, BP
-		ADDQ	sum+-40(SP), AX
-		ADDQ	$8192, AX
-		CMOVQLT	zero_0<>(SB), AX
-		SHRQ	$14, AX
-		CMPQ	u8max_2<>(SB), AX
-		CMOVQLT	u8max_2<>(SB), AX
-		ADDQ	$2, BX
-		MOVB	AL, (DI)
+		JE	end_11
+		MOVWQSX	(BX), AX
+		MOVWQSX	2(BX), DX
+		MOVL	(SI)(AX*1), X0
+		MOVL	(SI)(DX*1), X8
+		MOVWQSX	4(BX), AX
+		MOVWQSX	6(BX), DX
+		MOVL	(SI)(AX*1), X1
+		MOVL	(SI)(DX*1), X9
+		PUNPCKLLQ	X8, X0
+		PUNPCKLLQ	X9, X1
+		MOVWQSX	8(BX), AX
+		MOVWQSX	10(BX), DX
+		MOVL	(SI)(AX*1), X2
+		MOVL	(SI)(DX*1), X10
+		MOVWQSX	12(BX), AX
+		MOVWQSX	14(BX), DX
+		MOVL	(SI)(AX*1), X3
+		MOVL	(SI)(DX*1), X11
+		PUNPCKLLQ	X10, X2
+		PUNPCKLLQ	X11, X3
+		MOVWQSX	16(BX), AX
+		MOVWQSX	18(BX), DX
+		MOVL	(SI)(AX*1), X4
+		MOVL	(SI)(DX*1), X12
+		MOVWQSX	20(BX), AX
+		MOVWQSX	22(BX), DX
+		MOVL	(SI)(AX*1), X5
+		MOVL	(SI)(DX*1), X13
+		PUNPCKLLQ	X12, X4
+		PUNPCKLLQ	X13, X5
+		MOVWQSX	24(BX), AX
+		MOVWQSX	26(BX), DX
+		MOVL	(SI)(AX*1), X6
+		MOVL	(SI)(DX*1), X8
+		MOVWQSX	28(BX), AX
+		MOVWQSX	30(BX), DX
+		MOVL	(SI)(AX*1), X7
+		MOVL	(SI)(DX*1), X9
+		PUNPCKLLQ	X8, X6
+		PUNPCKLLQ	X9, X7
+		ADDQ	$32, BX
+		PUNPCKLBW	X15, X0
+		PMADDWL	(BP), X0
+		PUNPCKLBW	X15, X1
+		PMADDWL	16(BP), X1
+		PUNPCKLBW	X15, X2
+		PMADDWL	32(BP), X2
+		PUNPCKLBW	X15, X3
+		PMADDWL	48(BP), X3
+		MOVO	X0, X10
+		MOVO	X2, X11
+		SHUFPS	$221, X1, X10
+		SHUFPS	$221, X3, X11
+		SHUFPS	$136, X1, X0
+		SHUFPS	$136, X3, X2
+		PADDL	X10, X0
+		PADDL	X11, X2
+		PUNPCKLBW	X15, X4
+		PMADDWL	64(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	80(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	96(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	112(BP), X7
+		MOVO	X4, X12
+		MOVO	X6, X13
+		SHUFPS	$221, X5, X12
+		SHUFPS	$221, X7, X13
+		SHUFPS	$136, X5, X4
+		SHUFPS	$136, X7, X6
+		PADDL	X12, X4
+		PADDL	X13, X6
+		ADDQ	$128, BP
+		PADDL	X14, X0
+		PADDL	X14, X2
+		PADDL	X14, X4
+		PADDL	X14, X6
+		PSRAL	$14, X0
+		PSRAL	$14, X2
+		PSRAL	$14, X4
+		PSRAL	$14, X6
+		PACKSSLW	X2, X0
+		PACKSSLW	X6, X4
+		PACKUSWB	X4, X0
+		TESTQ	$8, tail+-16(SP)
+		JE	skip8_12
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_12:
+		TESTQ	$4, tail+-16(SP)
+		JE	skip4_13
+		MOVQ	X0, AX
+		MOVL	AX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_13:
+		TESTQ	$2, tail+-16(SP)
+		JE	skip2_14
+		MOVQ	X0, AX
+		MOVW	AX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_14:
+		TESTQ	$1, tail+-16(SP)
+		JE	skip1_15
+		MOVQ	X0, AX
+		MOVB	AX, (DI)
 		ADDQ	$1, DI
-		SUBQ	$1, CX
-		JNE	asmloop_7
-end_9:
+skip1_15:
+end_11:
 		MOVQ	srcref+-24(SP), SI
 		ADDQ	dstoff+-32(SP), DI
 		ADDQ	sp+128(FP), SI
 		MOVQ	SI, srcref+-24(SP)
 		SUBQ	$1, height+112(FP)
-		JNE	yloop_5
+		JNE	yloop_8
 		RET
 
-TEXT ·h8scale8Amd64(SB),4,$40-136
+TEXT ·h8scale8Amd64(SB),4,$32-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -299,22 +429,22 @@ TEXT ·h8scale8Amd64(SB),4,$40-136
 		ANDQ	$15, DX
 		MOVQ	BX, dstoff+-32(SP)
 		MOVQ	CX, simdroll+-8(SP)
-		MOVQ	DX, asmroll+-16(SP)
+		MOVQ	DX, tail+-16(SP)
 		MOVQ	src+24(FP), AX
 		MOVQ	AX, srcref+-24(SP)
 		MOVQ	taps+96(FP), DX
 		SUBQ	$2, DX
 		PXOR	X15, X15
-		MOVO	hbits_1<>(SB), X14
+		MOVO	hbits_0<>(SB), X14
 		MOVQ	src+24(FP), SI
 		MOVQ	dst+0(FP), DI
-yloop_10:
+yloop_16:
 		MOVQ	off+72(FP), BX
 		MOVQ	cof+48(FP), BP
 		MOVQ	simdroll+-8(SP), CX
 		ORQ	CX, CX
-		JE	nosimdloop_13
-simdloop_11:
+		JE	nosimdloop_18
+simdloop_17:
 		MOVWQSX	(BX), AX
 		MOVQ	(SI)(AX*1), X0
 		MOVWQSX	2(BX), DX
@@ -443,130 +573,35 @@ simdloop_11:
 		MOVOU	X0, (DI)
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	simdloop_11
-nosimdloop_13:
-		MOVQ	asmroll+-16(SP), CX
-		ORQ	CX, CX
-		JE	end_14
-asmloop_12:
-		MOVWQSX	(BX), DX
-		MOVBQZX	(SI)(DX*1), AX
-		MOVWQSX	(BP), DX
-		IMULQ	DX
-		MOVQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	1(SI)(DX*1), AX
-		MOVWQSX	2(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	2(SI)(DX*1), AX
-		MOVWQSX	4(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	3(SI)(DX*1), AX
-		MOVWQSX	6(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	4(SI)(DX*1), AX
-		MOVWQSX	8(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	5(SI)(DX*1), AX
-		MOVWQSX	10(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	6(SI)(DX*1), AX
-		MOVWQSX	12(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	7(SI)(DX*1), AX
-		MOVWQSX	14(BP), DX
-		IMULQ	DX
-		ADDQ	$16, BP
-		ADDQ	sum+-40(SP), AX
-		ADDQ	$8192, AX
-		CMOVQLT	zero_0<>(SB), AX
-		SHRQ	$14, AX
-		CMPQ	u8max_2<>(SB), AX
-		CMOVQLT	u8max_2<>(SB), AX
-		ADDQ	$2, BX
-		MOVB	AL, (DI)
-		ADDQ	$1, DI
-		SUBQ	$1, CX
-		JNE	asmloop_12
-end_14:
-		MOVQ	srcref+-24(SP), SI
-		ADDQ	dstoff+-32(SP), DI
-		ADDQ	sp+128(FP), SI
-		MOVQ	SI, srcref+-24(SP)
-		SUBQ	$1, height+112(FP)
-		JNE	yloop_10
-		RET
-
-TEXT ·h8scale10Amd64(SB),4,$40-136
-		MOVQ	dp+120(FP), BX
-		MOVQ	width+104(FP), CX
-		MOVQ	CX, DX
-		SUBQ	CX, BX
-		SHRQ	$4, CX
-		ANDQ	$15, DX
-		MOVQ	BX, dstoff+-32(SP)
-		MOVQ	CX, simdroll+-8(SP)
-		MOVQ	DX, asmroll+-16(SP)
-		MOVQ	src+24(FP), AX
-		MOVQ	AX, srcref+-24(SP)
-		MOVQ	taps+96(FP), DX
-		SUBQ	$2, DX
-		PXOR	X15, X15
-		MOVO	hbits_1<>(SB), X14
-		MOVQ	src+24(FP), SI
-		MOVQ	dst+0(FP), DI
-yloop_15:
-		MOVQ	off+72(FP), BX
-		MOVQ	cof+48(FP), BP
-		MOVQ	simdroll+-8(SP), CX
+		JNE	simdloop_17
+nosimdloop_18:
+		MOVQ	tail+-16(SP), CX
 		ORQ	CX, CX
-		JE	nosimdloop_18
-simdloop_16:
-		MOVWQSX	(BX), R8
-		MOVWQSX	2(BX), R9
-		MOVWQSX	4(BX), R10
-		MOVWQSX	6(BX), R11
-		PINSRW	$0, (SI)(R8*1), X0
-		PINSRW	$1, (SI)(R9*1), X0
-		PINSRW	$2, (SI)(R10*1), X0
-		PINSRW	$3, (SI)(R11*1), X0
-		MOVWQSX	8(BX), R8
-		MOVWQSX	10(BX), R9
-		MOVWQSX	12(BX), R10
-		MOVWQSX	14(BX), R11
-		PINSRW	$0, (SI)(R8*1), X1
-		PINSRW	$1, (SI)(R9*1), X1
-		PINSRW	$2, (SI)(R10*1), X1
-		PINSRW	$3, (SI)(R11*1), X1
-		MOVWQSX	16(BX), R8
-		MOVWQSX	18(BX), R9
-		MOVWQSX	20(BX), R10
-		MOVWQSX	22(BX), R11
-		PINSRW	$0, (SI)(R8*1), X2
-		PINSRW	$1, (SI)(R9*1), X2
-		PINSRW	$2, (SI)(R10*1), X2
-		PINSRW	$3, (SI)(R11*1), X2
-		MOVWQSX	24(BX), R8
-		MOVWQSX	26(BX), R9
-		MOVWQSX	28(BX), R10
-		MOVWQSX	30(BX), R11
-		PINSRW	$0, (SI)(R8*1), X3
-		PINSRW	$1, (SI)(R9*1), X3
-		PINSRW	$2, (SI)(R10*1), X3
-		PINSRW	$3, (SI)(R11*1), X3
-		ADDQ	$2, SI
+		JE	end_19
+		MOVWQSX	(BX), AX
+		MOVQ	(SI)(AX*1), X0
+		MOVWQSX	2(BX), DX
+		MOVQ	(SI)(DX*1), X1
+		MOVWQSX	4(BX), AX
+		MOVQ	(SI)(AX*1), X2
+		MOVWQSX	6(BX), DX
+		MOVQ	(SI)(DX*1), X3
+		MOVWQSX	8(BX), AX
+		MOVQ	(SI)(AX*1), X4
+		MOVWQSX	10(BX), DX
+		MOVQ	(SI)(DX*1), X5
+		MOVWQSX	12(BX), AX
+		MOVQ	(SI)(AX*1), X6
+		MOVWQSX	14(BX), DX
+		MOVQ	(SI)(DX*1), X7
+		MOVWQSX	16(BX), AX
+		MOVQ	(SI)(AX*1), X8
+		MOVWQSX	18(BX), DX
+		MOVQ	(SI)(DX*1), X9
+		MOVWQSX	20(BX), AX
+		MOVQ	(SI)(AX*1), X10
+		MOVWQSX	22(BX), DX
+		MOVQ	(SI)(DX*1), X11
 		PUNPCKLBW	X15, X0
 		PMADDWL	(BP), X0
 		PUNPCKLBW	X15, X1
@@ -575,19 +610,213 @@ simdloop_16:
 		PMADDWL	32(BP), X2
 		PUNPCKLBW	X15, X3
 		PMADDWL	48(BP), X3
-		ADDQ	$64, BP
-		MOVWQSX	(BX), R8
-		MOVWQSX	2(BX), R9
-		MOVWQSX	4(BX), R10
-		MOVWQSX	6(BX), R11
-		PINSRW	$0, (SI)(R8*1), X4
-		PINSRW	$1, (SI)(R9*1), X4
-		PINSRW	$2, (SI)(R10*1), X4
-		PINSRW	$3, (SI)(R11*1), X4
-		MOVWQSX	8(BX), R8
-		MOVWQSX	10(BX), R9
-		MOVWQSX	12(BX), R10
-		MOVWQSX	14(BX), R11
+		MOVO	X0, X12
+		MOVO	X2, X13
+		PUNPCKLQDQ	X1, X0
+		PUNPCKHQDQ	X1, X12
+		PADDL	X12, X0
+		PUNPCKLQDQ	X3, X2
+		PUNPCKHQDQ	X3, X13
+		PADDL	X13, X2
+		MOVO	X0, X12
+		SHUFPS	$136, X2, X0
+		SHUFPS	$221, X2, X12
+		PADDL	X12, X0
+		PUNPCKLBW	X15, X4
+		PMADDWL	64(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	80(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	96(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	112(BP), X7
+		MOVO	X4, X1
+		MOVO	X6, X2
+		PUNPCKLQDQ	X5, X4
+		PUNPCKHQDQ	X5, X1
+		PADDL	X1, X4
+		PUNPCKLQDQ	X7, X6
+		PUNPCKHQDQ	X7, X2
+		PADDL	X2, X6
+		MOVO	X4, X1
+		SHUFPS	$136, X6, X4
+		SHUFPS	$221, X6, X1
+		PADDL	X1, X4
+		MOVWQSX	24(BX), AX
+		MOVQ	(SI)(AX*1), X1
+		MOVWQSX	26(BX), DX
+		MOVQ	(SI)(DX*1), X2
+		MOVWQSX	28(BX), AX
+		MOVQ	(SI)(AX*1), X3
+		MOVWQSX	30(BX), DX
+		MOVQ	(SI)(DX*1), X5
+		ADDQ	$32, BX
+		PUNPCKLBW	X15, X8
+		PMADDWL	128(BP), X8
+		PUNPCKLBW	X15, X9
+		PMADDWL	144(BP), X9
+		PUNPCKLBW	X15, X10
+		PMADDWL	160(BP), X10
+		PUNPCKLBW	X15, X11
+		PMADDWL	176(BP), X11
+		MOVO	X8, X12
+		MOVO	X10, X13
+		PUNPCKLQDQ	X9, X8
+		PUNPCKHQDQ	X9, X12
+		PADDL	X12, X8
+		PUNPCKLQDQ	X11, X10
+		PUNPCKHQDQ	X11, X13
+		PADDL	X13, X10
+		MOVO	X8, X12
+		SHUFPS	$136, X10, X8
+		SHUFPS	$221, X10, X12
+		PADDL	X12, X8
+		PUNPCKLBW	X15, X1
+		PMADDWL	192(BP), X1
+		PUNPCKLBW	X15, X2
+		PMADDWL	208(BP), X2
+		PUNPCKLBW	X15, X3
+		PMADDWL	224(BP), X3
+		PUNPCKLBW	X15, X5
+		PMADDWL	240(BP), X5
+		MOVO	X1, X10
+		MOVO	X3, X11
+		PUNPCKLQDQ	X2, X1
+		PUNPCKHQDQ	X2, X10
+		PADDL	X10, X1
+		PUNPCKLQDQ	X5, X3
+		PUNPCKHQDQ	X5, X11
+		PADDL	X11, X3
+		MOVO	X1, X10
+		SHUFPS	$136, X3, X1
+		SHUFPS	$221, X3, X10
+		PADDL	X10, X1
+		ADDQ	$256, BP
+		PADDL	X14, X0
+		PADDL	X14, X4
+		PADDL	X14, X8
+		PADDL	X14, X1
+		PSRAL	$14, X0
+		PSRAL	$14, X4
+		PSRAL	$14, X8
+		PSRAL	$14, X1
+		PACKSSLW	X4, X0
+		PACKSSLW	X1, X8
+		PACKUSWB	X8, X0
+		TESTQ	$8, tail+-16(SP)
+		JE	skip8_20
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_20:
+		TESTQ	$4, tail+-16(SP)
+		JE	skip4_21
+		MOVQ	X0, AX
+		MOVL	AX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_21:
+		TESTQ	$2, tail+-16(SP)
+		JE	skip2_22
+		MOVQ	X0, AX
+		MOVW	AX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_22:
+		TESTQ	$1, tail+-16(SP)
+		JE	skip1_23
+		MOVQ	X0, AX
+		MOVB	AX, (DI)
+		ADDQ	$1, DI
+skip1_23:
+end_19:
+		MOVQ	srcref+-24(SP), SI
+		ADDQ	dstoff+-32(SP), DI
+		ADDQ	sp+128(FP), SI
+		MOVQ	SI, srcref+-24(SP)
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_16
+		RET
+
+TEXT ·h8scale10Amd64(SB),4,$32-136
+		MOVQ	dp+120(FP), BX
+		MOVQ	width+104(FP), CX
+		MOVQ	CX, DX
+		SUBQ	CX, BX
+		SHRQ	$4, CX
+		ANDQ	$15, DX
+		MOVQ	BX, dstoff+-32(SP)
+		MOVQ	CX, simdroll+-8(SP)
+		MOVQ	DX, tail+-16(SP)
+		MOVQ	src+24(FP), AX
+		MOVQ	AX, srcref+-24(SP)
+		MOVQ	taps+96(FP), DX
+		SUBQ	$2, DX
+		PXOR	X15, X15
+		MOVO	hbits_0<>(SB), X14
+		MOVQ	src+24(FP), SI
+		MOVQ	dst+0(FP), DI
+yloop_24:
+		MOVQ	off+72(FP), BX
+		MOVQ	cof+48(FP), BP
+		MOVQ	simdroll+-8(SP), CX
+		ORQ	CX, CX
+		JE	nosimdloop_26
+simdloop_25:
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X0
+		PINSRW	$1, (SI)(R9*1), X0
+		PINSRW	$2, (SI)(R10*1), X0
+		PINSRW	$3, (SI)(R11*1), X0
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X1
+		PINSRW	$1, (SI)(R9*1), X1
+		PINSRW	$2, (SI)(R10*1), X1
+		PINSRW	$3, (SI)(R11*1), X1
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X2
+		PINSRW	$1, (SI)(R9*1), X2
+		PINSRW	$2, (SI)(R10*1), X2
+		PINSRW	$3, (SI)(R11*1), X2
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X3
+		PINSRW	$1, (SI)(R9*1), X3
+		PINSRW	$2, (SI)(R10*1), X3
+		PINSRW	$3, (SI)(R11*1), X3
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X0
+		PMADDWL	(BP), X0
+		PUNPCKLBW	X15, X1
+		PMADDWL	16(BP), X1
+		PUNPCKLBW	X15, X2
+		PMADDWL	32(BP), X2
+		PUNPCKLBW	X15, X3
+		PMADDWL	48(BP), X3
+		ADDQ	$64, BP
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
 		PINSRW	$0, (SI)(R8*1), X5
 		PINSRW	$1, (SI)(R9*1), X5
 		PINSRW	$2, (SI)(R10*1), X5
@@ -777,83 +1006,927 @@ simdloop_16:
 		MOVOU	X0, (DI)
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	simdloop_16
-nosimdloop_18:
-		MOVQ	asmroll+-16(SP), CX
+		JNE	simdloop_25
+nosimdloop_26:
+		MOVQ	tail+-16(SP), CX
 		ORQ	CX, CX
-		JE	end_19
-asmloop_17:
-		MOVWQSX	(BX), DX
-		MOVBQZX	(SI)(DX*1), AX
-		MOVWQSX	(BP), DX
-		IMULQ	DX
-		MOVQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	1(SI)(DX*1), AX
-		MOVWQSX	2(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	2(SI)(DX*1), AX
-		MOVWQSX	4(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	3(SI)(DX*1), AX
-		MOVWQSX	6(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	4(SI)(DX*1), AX
-		MOVWQSX	8(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	5(SI)(DX*1), AX
-		MOVWQSX	10(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	6(SI)(DX*1), AX
-		MOVWQSX	12(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	7(SI)(DX*1), AX
-		MOVWQSX	14(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	8(SI)(DX*1), AX
-		MOVWQSX	16(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	9(SI)(DX*1), AX
-		MOVWQSX	18(BP), DX
-		IMULQ	DX
-		ADDQ	$20, BP
-		ADDQ	sum+-40(SP), AX
-		ADDQ	$8192, AX
-		CMOVQLT	zero_0<>(SB), AX
-		SHRQ	$14, AX
-		CMPQ	u8max_2<>(SB), AX
-		CMOVQLT	u8max_2<>(SB), AX
-		ADDQ	$2, BX
-		MOVB	AL, (DI)
+		JE	end_27
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X0
+		PINSRW	$1, (SI)(R9*1), X0
+		PINSRW	$2, (SI)(R10*1), X0
+		PINSRW	$3, (SI)(R11*1), X0
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X1
+		PINSRW	$1, (SI)(R9*1), X1
+		PINSRW	$2, (SI)(R10*1), X1
+		PINSRW	$3, (SI)(R11*1), X1
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X2
+		PINSRW	$1, (SI)(R9*1), X2
+		PINSRW	$2, (SI)(R10*1), X2
+		PINSRW	$3, (SI)(R11*1), X2
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X3
+		PINSRW	$1, (SI)(R9*1), X3
+		PINSRW	$2, (SI)(R10*1), X3
+		PINSRW	$3, (SI)(R11*1), X3
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X0
+		PMADDWL	(BP), X0
+		PUNPCKLBW	X15, X1
+		PMADDWL	16(BP), X1
+		PUNPCKLBW	X15, X2
+		PMADDWL	32(BP), X2
+		PUNPCKLBW	X15, X3
+		PMADDWL	48(BP), X3
+		ADDQ	$64, BP
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVQ	taps+96(FP), AX
+		SUBQ	AX, SI
+		ADDQ	$32, BX
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, tail+-16(SP)
+		JE	skip8_28
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_28:
+		TESTQ	$4, tail+-16(SP)
+		JE	skip4_29
+		MOVQ	X0, AX
+		MOVL	AX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_29:
+		TESTQ	$2, tail+-16(SP)
+		JE	skip2_30
+		MOVQ	X0, AX
+		MOVW	AX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_30:
+		TESTQ	$1, tail+-16(SP)
+		JE	skip1_31
+		MOVQ	X0, AX
+		MOVB	AX, (DI)
+		ADDQ	$1, DI
+skip1_31:
+end_27:
+		MOVQ	srcref+-24(SP), SI
+		ADDQ	dstoff+-32(SP), DI
+		ADDQ	sp+128(FP), SI
+		MOVQ	SI, srcref+-24(SP)
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_24
+		RET
+
+TEXT ·h8scale12Amd64(SB),4,$32-136
+		MOVQ	dp+120(FP), BX
+		MOVQ	width+104(FP), CX
+		MOVQ	CX, DX
+		SUBQ	CX, BX
+		SHRQ	$4, CX
+		ANDQ	$15, DX
+		MOVQ	BX, dstoff+-32(SP)
+		MOVQ	CX, simdroll+-8(SP)
+		MOVQ	DX, tail+-16(SP)
+		MOVQ	src+24(FP), AX
+		MOVQ	AX, srcref+-24(SP)
+		MOVQ	taps+96(FP), DX
+		SUBQ	$2, DX
+		PXOR	X15, X15
+		MOVO	hbits_0<>(SB), X14
+		MOVQ	src+24(FP), SI
+		MOVQ	dst+0(FP), DI
+yloop_32:
+		MOVQ	off+72(FP), BX
+		MOVQ	cof+48(FP), BP
+		MOVQ	simdroll+-8(SP), CX
+		ORQ	CX, CX
+		JE	nosimdloop_34
+simdloop_33:
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X0
+		PINSRW	$1, (SI)(R9*1), X0
+		PINSRW	$2, (SI)(R10*1), X0
+		PINSRW	$3, (SI)(R11*1), X0
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X1
+		PINSRW	$1, (SI)(R9*1), X1
+		PINSRW	$2, (SI)(R10*1), X1
+		PINSRW	$3, (SI)(R11*1), X1
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X2
+		PINSRW	$1, (SI)(R9*1), X2
+		PINSRW	$2, (SI)(R10*1), X2
+		PINSRW	$3, (SI)(R11*1), X2
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X3
+		PINSRW	$1, (SI)(R9*1), X3
+		PINSRW	$2, (SI)(R10*1), X3
+		PINSRW	$3, (SI)(R11*1), X3
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X0
+		PMADDWL	(BP), X0
+		PUNPCKLBW	X15, X1
+		PMADDWL	16(BP), X1
+		PUNPCKLBW	X15, X2
+		PMADDWL	32(BP), X2
+		PUNPCKLBW	X15, X3
+		PMADDWL	48(BP), X3
+		ADDQ	$64, BP
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVQ	taps+96(FP), AX
+		SUBQ	AX, SI
+		ADDQ	$32, BX
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$16, DI
+		SUBQ	$1, CX
+		JNE	simdloop_33
+nosimdloop_34:
+		MOVQ	tail+-16(SP), CX
+		ORQ	CX, CX
+		JE	end_35
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X0
+		PINSRW	$1, (SI)(R9*1), X0
+		PINSRW	$2, (SI)(R10*1), X0
+		PINSRW	$3, (SI)(R11*1), X0
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X1
+		PINSRW	$1, (SI)(R9*1), X1
+		PINSRW	$2, (SI)(R10*1), X1
+		PINSRW	$3, (SI)(R11*1), X1
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X2
+		PINSRW	$1, (SI)(R9*1), X2
+		PINSRW	$2, (SI)(R10*1), X2
+		PINSRW	$3, (SI)(R11*1), X2
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X3
+		PINSRW	$1, (SI)(R9*1), X3
+		PINSRW	$2, (SI)(R10*1), X3
+		PINSRW	$3, (SI)(R11*1), X3
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X0
+		PMADDWL	(BP), X0
+		PUNPCKLBW	X15, X1
+		PMADDWL	16(BP), X1
+		PUNPCKLBW	X15, X2
+		PMADDWL	32(BP), X2
+		PUNPCKLBW	X15, X3
+		PMADDWL	48(BP), X3
+		ADDQ	$64, BP
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVWQSX	(BX), R8
+		MOVWQSX	2(BX), R9
+		MOVWQSX	4(BX), R10
+		MOVWQSX	6(BX), R11
+		PINSRW	$0, (SI)(R8*1), X4
+		PINSRW	$1, (SI)(R9*1), X4
+		PINSRW	$2, (SI)(R10*1), X4
+		PINSRW	$3, (SI)(R11*1), X4
+		MOVWQSX	8(BX), R8
+		MOVWQSX	10(BX), R9
+		MOVWQSX	12(BX), R10
+		MOVWQSX	14(BX), R11
+		PINSRW	$0, (SI)(R8*1), X5
+		PINSRW	$1, (SI)(R9*1), X5
+		PINSRW	$2, (SI)(R10*1), X5
+		PINSRW	$3, (SI)(R11*1), X5
+		MOVWQSX	16(BX), R8
+		MOVWQSX	18(BX), R9
+		MOVWQSX	20(BX), R10
+		MOVWQSX	22(BX), R11
+		PINSRW	$0, (SI)(R8*1), X6
+		PINSRW	$1, (SI)(R9*1), X6
+		PINSRW	$2, (SI)(R10*1), X6
+		PINSRW	$3, (SI)(R11*1), X6
+		MOVWQSX	24(BX), R8
+		MOVWQSX	26(BX), R9
+		MOVWQSX	28(BX), R10
+		MOVWQSX	30(BX), R11
+		PINSRW	$0, (SI)(R8*1), X7
+		PINSRW	$1, (SI)(R9*1), X7
+		PINSRW	$2, (SI)(R10*1), X7
+		PINSRW	$3, (SI)(R11*1), X7
+		ADDQ	$2, SI
+		PUNPCKLBW	X15, X4
+		PMADDWL	(BP), X4
+		PUNPCKLBW	X15, X5
+		PMADDWL	16(BP), X5
+		PUNPCKLBW	X15, X6
+		PMADDWL	32(BP), X6
+		PUNPCKLBW	X15, X7
+		PMADDWL	48(BP), X7
+		ADDQ	$64, BP
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		MOVQ	taps+96(FP), AX
+		SUBQ	AX, SI
+		ADDQ	$32, BX
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, tail+-16(SP)
+		JE	skip8_36
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_36:
+		TESTQ	$4, tail+-16(SP)
+		JE	skip4_37
+		MOVQ	X0, AX
+		MOVL	AX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_37:
+		TESTQ	$2, tail+-16(SP)
+		JE	skip2_38
+		MOVQ	X0, AX
+		MOVW	AX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_38:
+		TESTQ	$1, tail+-16(SP)
+		JE	skip1_39
+		MOVQ	X0, AX
+		MOVB	AX, (DI)
 		ADDQ	$1, DI
-		SUBQ	$1, CX
-		JNE	asmloop_17
-end_19:
+skip1_39:
+end_35:
 		MOVQ	srcref+-24(SP), SI
 		ADDQ	dstoff+-32(SP), DI
 		ADDQ	sp+128(FP), SI
 		MOVQ	SI, srcref+-24(SP)
 		SUBQ	$1, height+112(FP)
-		JNE	yloop_15
+		JNE	yloop_32
 		RET
 
-TEXT ·h8scale12Amd64(SB),4,$40-136
+TEXT ·h8scaleNAmd64(SB),4,$48-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -862,22 +1935,23 @@ TEXT ·h8scale12Amd64(SB),4,$40-136
 		ANDQ	$15, DX
 		MOVQ	BX, dstoff+-32(SP)
 		MOVQ	CX, simdroll+-8(SP)
-		MOVQ	DX, asmroll+-16(SP)
+		MOVQ	DX, tail+-16(SP)
 		MOVQ	src+24(FP), AX
 		MOVQ	AX, srcref+-24(SP)
 		MOVQ	taps+96(FP), DX
 		SUBQ	$2, DX
+		MOVQ	DX, inner+-48(SP)
 		PXOR	X15, X15
-		MOVO	hbits_1<>(SB), X14
+		MOVO	hbits_0<>(SB), X14
 		MOVQ	src+24(FP), SI
 		MOVQ	dst+0(FP), DI
-yloop_20:
+yloop_40:
 		MOVQ	off+72(FP), BX
 		MOVQ	cof+48(FP), BP
 		MOVQ	simdroll+-8(SP), CX
 		ORQ	CX, CX
-		JE	nosimdloop_23
-simdloop_21:
+		JE	nosimdloop_42
+simdloop_41:
 		MOVWQSX	(BX), R8
 		MOVWQSX	2(BX), R9
 		MOVWQSX	4(BX), R10
@@ -920,6 +1994,11 @@ simdloop_21:
 		PUNPCKLBW	X15, X3
 		PMADDWL	48(BP), X3
 		ADDQ	$64, BP
+		MOVQ	DI, dstref+-40(SP)
+		MOVQ	inner+-48(SP), DI
+		ORQ	DI, DI
+		JE	noloop_44
+loop_45:
 		MOVWQSX	(BX), R8
 		MOVWQSX	2(BX), R9
 		MOVWQSX	4(BX), R10
@@ -966,52 +2045,79 @@ simdloop_21:
 		PADDL	X5, X1
 		PADDL	X6, X2
 		PADDL	X7, X3
+		SUBQ	$2, DI
+		JNE	loop_45
+noloop_44:
+		MOVQ	dstref+-40(SP), DI
+		MOVQ	taps+96(FP), AX
+		SUBQ	AX, SI
+		ADDQ	$32, BX
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$16, DI
+		SUBQ	$1, CX
+		JNE	simdloop_41
+nosimdloop_42:
+		MOVQ	tail+-16(SP), CX
+		ORQ	CX, CX
+		JE	end_43
 		MOVWQSX	(BX), R8
 		MOVWQSX	2(BX), R9
 		MOVWQSX	4(BX), R10
 		MOVWQSX	6(BX), R11
-		PINSRW	$0, (SI)(R8*1), X4
-		PINSRW	$1, (SI)(R9*1), X4
-		PINSRW	$2, (SI)(R10*1), X4
-		PINSRW	$3, (SI)(R11*1), X4
+		PINSRW	$0, (SI)(R8*1), X0
+		PINSRW	$1, (SI)(R9*1), X0
+		PINSRW	$2, (SI)(R10*1), X0
+		PINSRW	$3, (SI)(R11*1), X0
 		MOVWQSX	8(BX), R8
 		MOVWQSX	10(BX), R9
 		MOVWQSX	12(BX), R10
 		MOVWQSX	14(BX), R11
-		PINSRW	$0, (SI)(R8*1), X5
-		PINSRW	$1, (SI)(R9*1), X5
-		PINSRW	$2, (SI)(R10*1), X5
-		PINSRW	$3, (SI)(R11*1), X5
+		PINSRW	$0, (SI)(R8*1), X1
+		PINSRW	$1, (SI)(R9*1), X1
+		PINSRW	$2, (SI)(R10*1), X1
+		PINSRW	$3, (SI)(R11*1), X1
 		MOVWQSX	16(BX), R8
 		MOVWQSX	18(BX), R9
 		MOVWQSX	20(BX), R10
 		MOVWQSX	22(BX), R11
-		PINSRW	$0, (SI)(R8*1), X6
-		PINSRW	$1, (SI)(R9*1), X6
-		PINSRW	$2, (SI)(R10*1), X6
-		PINSRW	$3, (SI)(R11*1), X6
+		PINSRW	$0, (SI)(R8*1), X2
+		PINSRW	$1, (SI)(R9*1), X2
+		PINSRW	$2, (SI)(R10*1), X2
+		PINSRW	$3, (SI)(R11*1), X2
 		MOVWQSX	24(BX), R8
 		MOVWQSX	26(BX), R9
 		MOVWQSX	28(BX), R10
 		MOVWQSX	30(BX), R11
-		PINSRW	$0, (SI)(R8*1), X7
-		PINSRW	$1, (SI)(R9*1), X7
-		PINSRW	$2, (SI)(R10*1), X7
-		PINSRW	$3, (SI)(R11*1), X7
+		PINSRW	$0, (SI)(R8*1), X3
+		PINSRW	$1, (SI)(R9*1), X3
+		PINSRW	$2, (SI)(R10*1), X3
+		PINSRW	$3, (SI)(R11*1), X3
 		ADDQ	$2, SI
-		PUNPCKLBW	X15, X4
-		PMADDWL	(BP), X4
-		PUNPCKLBW	X15, X5
-		PMADDWL	16(BP), X5
-		PUNPCKLBW	X15, X6
-		PMADDWL	32(BP), X6
-		PUNPCKLBW	X15, X7
-		PMADDWL	48(BP), X7
+		PUNPCKLBW	X15, X0
+		PMADDWL	(BP), X0
+		PUNPCKLBW	X15, X1
+		PMADDWL	16(BP), X1
+		PUNPCKLBW	X15, X2
+		PMADDWL	32(BP), X2
+		PUNPCKLBW	X15, X3
+		PMADDWL	48(BP), X3
 		ADDQ	$64, BP
-		PADDL	X4, X0
-		PADDL	X5, X1
-		PADDL	X6, X2
-		PADDL	X7, X3
+		MOVQ	DI, dstref+-40(SP)
+		MOVQ	inner+-48(SP), DI
+		ORQ	DI, DI
+		JE	noloop_46
+loop_47:
 		MOVWQSX	(BX), R8
 		MOVWQSX	2(BX), R9
 		MOVWQSX	4(BX), R10
@@ -1058,52 +2164,171 @@ simdloop_21:
 		PADDL	X5, X1
 		PADDL	X6, X2
 		PADDL	X7, X3
+		SUBQ	$2, DI
+		JNE	loop_47
+noloop_46:
+		MOVQ	dstref+-40(SP), DI
+		MOVQ	taps+96(FP), AX
+		SUBQ	AX, SI
+		ADDQ	$32, BX
+		PADDL	X14, X0
+		PADDL	X14, X1
+		PADDL	X14, X2
+		PADDL	X14, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, tail+-16(SP)
+		JE	skip8_48
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_48:
+		TESTQ	$4, tail+-16(SP)
+		JE	skip4_49
+		MOVQ	X0, AX
+		MOVL	AX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_49:
+		TESTQ	$2, tail+-16(SP)
+		JE	skip2_50
+		MOVQ	X0, AX
+		MOVW	AX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_50:
+		TESTQ	$1, tail+-16(SP)
+		JE	skip1_51
+		MOVQ	X0, AX
+		MOVB	AX, (DI)
+		ADDQ	$1, DI
+skip1_51:
+end_43:
+		MOVQ	srcref+-24(SP), SI
+		ADDQ	dstoff+-32(SP), DI
+		ADDQ	sp+128(FP), SI
+		MOVQ	SI, srcref+-24(SP)
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_40
+		RET
+DATA	ringthr_1<>+0x00(SB)/8, $0x0200020002000200
+DATA	ringthr_1<>+0x08(SB)/8, $0x0200020002000200
+GLOBL	ringthr_1<>(SB), 8, $16
+DATA	ones_2<>+0x00(SB)/8, $0xFFFFFFFFFFFFFFFF
+DATA	ones_2<>+0x08(SB)/8, $0xFFFFFFFFFFFFFFFF
+GLOBL	ones_2<>(SB), 8, $16
+
+TEXT ·h8ringNAmd64(SB),4,$64-144
+		MOVQ	strength+136(FP), AX
+		MOVQ	$281479271743489, DX
+		IMULQ	DX
+		MOVQ	AX, strength+-16(SP)
+		MOVQ	AX, strengthhi+-8(SP)
+		MOVQ	dp+120(FP), BX
+		MOVQ	width+104(FP), CX
+		MOVQ	CX, DX
+		SUBQ	CX, BX
+		SHRQ	$4, CX
+		ANDQ	$15, DX
+		MOVQ	BX, dstoff+-48(SP)
+		MOVQ	CX, simdroll+-24(SP)
+		MOVQ	DX, tail+-32(SP)
+		MOVQ	src+24(FP), AX
+		MOVQ	AX, srcref+-40(SP)
+		MOVQ	taps+96(FP), DX
+		SUBQ	$2, DX
+		MOVQ	DX, inner+-64(SP)
+		PXOR	X15, X15
+		MOVO	hbits_0<>(SB), X14
+		MOVQ	src+24(FP), SI
+		MOVQ	dst+0(FP), DI
+yloop_52:
+		MOVQ	off+72(FP), BX
+		MOVQ	cof+48(FP), BP
+		MOVQ	simdroll+-24(SP), CX
+		ORQ	CX, CX
+		JE	nosimdloop_54
+simdloop_53:
+		PCMPEQB	X8, X8
+		PXOR	X9, X9
+		PCMPEQB	X10, X10
+		PXOR	X11, X11
 		MOVWQSX	(BX), R8
 		MOVWQSX	2(BX), R9
 		MOVWQSX	4(BX), R10
 		MOVWQSX	6(BX), R11
-		PINSRW	$0, (SI)(R8*1), X4
-		PINSRW	$1, (SI)(R9*1), X4
-		PINSRW	$2, (SI)(R10*1), X4
-		PINSRW	$3, (SI)(R11*1), X4
+		PINSRW	$0, (SI)(R8*1), X0
+		PINSRW	$1, (SI)(R9*1), X0
+		PINSRW	$2, (SI)(R10*1), X0
+		PINSRW	$3, (SI)(R11*1), X0
 		MOVWQSX	8(BX), R8
 		MOVWQSX	10(BX), R9
 		MOVWQSX	12(BX), R10
 		MOVWQSX	14(BX), R11
-		PINSRW	$0, (SI)(R8*1), X5
-		PINSRW	$1, (SI)(R9*1), X5
-		PINSRW	$2, (SI)(R10*1), X5
-		PINSRW	$3, (SI)(R11*1), X5
+		PINSRW	$0, (SI)(R8*1), X1
+		PINSRW	$1, (SI)(R9*1), X1
+		PINSRW	$2, (SI)(R10*1), X1
+		PINSRW	$3, (SI)(R11*1), X1
 		MOVWQSX	16(BX), R8
 		MOVWQSX	18(BX), R9
 		MOVWQSX	20(BX), R10
 		MOVWQSX	22(BX), R11
-		PINSRW	$0, (SI)(R8*1), X6
-		PINSRW	$1, (SI)(R9*1), X6
-		PINSRW	$2, (SI)(R10*1), X6
-		PINSRW	$3, (SI)(R11*1), X6
+		PINSRW	$0, (SI)(R8*1), X2
+		PINSRW	$1, (SI)(R9*1), X2
+		PINSRW	$2, (SI)(R10*1), X2
+		PINSRW	$3, (SI)(R11*1), X2
 		MOVWQSX	24(BX), R8
 		MOVWQSX	26(BX), R9
 		MOVWQSX	28(BX), R10
 		MOVWQSX	30(BX), R11
-		PINSRW	$0, (SI)(R8*1), X7
-		PINSRW	$1, (SI)(R9*1), X7
-		PINSRW	$2, (SI)(R10*1), X7
-		PINSRW	$3, (SI)(R11*1), X7
+		PINSRW	$0, (SI)(R8*1), X3
+		PINSRW	$1, (SI)(R9*1), X3
+		PINSRW	$2, (SI)(R10*1), X3
+		PINSRW	$3, (SI)(R11*1), X3
 		ADDQ	$2, SI
-		PUNPCKLBW	X15, X4
-		PMADDWL	(BP), X4
-		PUNPCKLBW	X15, X5
-		PMADDWL	16(BP), X5
-		PUNPCKLBW	X15, X6
-		PMADDWL	32(BP), X6
-		PUNPCKLBW	X15, X7
-		PMADDWL	48(BP), X7
+		MOVO	(BP), X12
+		PCMPGTW	ringthr_1<>(SB), X12
+		MOVO	16(BP), X13
+		PCMPGTW	ringthr_1<>(SB), X13
+		PACKSSWB	X13, X12
+		MOVO	X0, X13
+		PUNPCKLQDQ	X1, X13
+		PAND	X12, X13
+		PMAXUB	X13, X9
+		PXOR	ones_2<>(SB), X12
+		POR	X13, X12
+		PMINUB	X12, X8
+		MOVO	32(BP), X12
+		PCMPGTW	ringthr_1<>(SB), X12
+		MOVO	48(BP), X13
+		PCMPGTW	ringthr_1<>(SB), X13
+		PACKSSWB	X13, X12
+		MOVO	X2, X13
+		PUNPCKLQDQ	X3, X13
+		PAND	X12, X13
+		PMAXUB	X13, X11
+		PXOR	ones_2<>(SB), X12
+		POR	X13, X12
+		PMINUB	X12, X10
+		PUNPCKLBW	X15, X0
+		PMADDWL	(BP), X0
+		PUNPCKLBW	X15, X1
+		PMADDWL	16(BP), X1
+		PUNPCKLBW	X15, X2
+		PMADDWL	32(BP), X2
+		PUNPCKLBW	X15, X3
+		PMADDWL	48(BP), X3
 		ADDQ	$64, BP
-		PADDL	X4, X0
-		PADDL	X5, X1
-		PADDL	X6, X2
-		PADDL	X7, X3
+		MOVQ	DI, dstref+-56(SP)
+		MOVQ	inner+-64(SP), DI
+		ORQ	DI, DI
+		JE	noloop_56
+loop_57:
 		MOVWQSX	(BX), R8
 		MOVWQSX	2(BX), R9
 		MOVWQSX	4(BX), R10
@@ -1137,6 +2362,30 @@ simdloop_21:
 		PINSRW	$2, (SI)(R10*1), X7
 		PINSRW	$3, (SI)(R11*1), X7
 		ADDQ	$2, SI
+		MOVO	(BP), X12
+		PCMPGTW	ringthr_1<>(SB), X12
+		MOVO	16(BP), X13
+		PCMPGTW	ringthr_1<>(SB), X13
+		PACKSSWB	X13, X12
+		MOVO	X4, X13
+		PUNPCKLQDQ	X5, X13
+		PAND	X12, X13
+		PMAXUB	X13, X9
+		PXOR	ones_2<>(SB), X12
+		POR	X13, X12
+		PMINUB	X12, X8
+		MOVO	32(BP), X12
+		PCMPGTW	ringthr_1<>(SB), X12
+		MOVO	48(BP), X13
+		PCMPGTW	ringthr_1<>(SB), X13
+		PACKSSWB	X13, X12
+		MOVO	X6, X13
+		PUNPCKLQDQ	X7, X13
+		PAND	X12, X13
+		PMAXUB	X13, X11
+		PXOR	ones_2<>(SB), X12
+		POR	X13, X12
+		PMINUB	X12, X10
 		PUNPCKLBW	X15, X4
 		PMADDWL	(BP), X4
 		PUNPCKLBW	X15, X5
@@ -1150,6 +2399,10 @@ simdloop_21:
 		PADDL	X5, X1
 		PADDL	X6, X2
 		PADDL	X7, X3
+		SUBQ	$2, DI
+		JNE	loop_57
+noloop_56:
+		MOVQ	dstref+-56(SP), DI
 		MOVQ	taps+96(FP), AX
 		SUBQ	AX, SI
 		ADDQ	$32, BX
@@ -1164,121 +2417,85 @@ simdloop_21:
 		PACKSSLW	X1, X0
 		PACKSSLW	X3, X2
 		PACKUSWB	X2, X0
+		MOVO	X8, X12
+		PSRLW	$8, X12
+		PSLLW	$8, X8
+		PSRLW	$8, X8
+		PMINUB	X12, X8
+		MOVO	X10, X13
+		PSRLW	$8, X13
+		PSLLW	$8, X10
+		PSRLW	$8, X10
+		PMINUB	X13, X10
+		PACKUSWB	X10, X8
+		MOVO	X9, X12
+		PSRLW	$8, X12
+		PSLLW	$8, X9
+		PSRLW	$8, X9
+		PMAXUB	X12, X9
+		MOVO	X11, X13
+		PSRLW	$8, X13
+		PSLLW	$8, X11
+		PSRLW	$8, X11
+		PMAXUB	X13, X11
+		PACKUSWB	X11, X9
+		MOVO	X8, X1
+		PMINUB	X9, X1
+		PCMPEQB	X8, X1
+		PMAXUB	X0, X8
+		PMINUB	X9, X8
+		PAND	X1, X8
+		PANDN	X0, X1
+		POR	X1, X8
+		MOVOU	strength+-16(SP), X7
+		MOVO	X0, X1
+		PUNPCKLBW	X15, X1
+		MOVO	X8, X2
+		PUNPCKLBW	X15, X2
+		PSUBW	X1, X2
+		MOVO	X2, X3
+		PMULHW	X7, X3
+		PMULLW	X7, X2
+		MOVO	X2, X4
+		PUNPCKLWL	X3, X2
+		PUNPCKHWL	X3, X4
+		PADDL	X14, X2
+		PADDL	X14, X4
+		PSRAL	$14, X2
+		PSRAL	$14, X4
+		PACKSSLW	X4, X2
+		PADDW	X1, X2
+		MOVO	X0, X1
+		PUNPCKHBW	X15, X1
+		MOVO	X8, X3
+		PUNPCKHBW	X15, X3
+		PSUBW	X1, X3
+		MOVO	X3, X4
+		PMULHW	X7, X4
+		PMULLW	X7, X3
+		MOVO	X3, X9
+		PUNPCKLWL	X4, X3
+		PUNPCKHWL	X4, X9
+		PADDL	X14, X3
+		PADDL	X14, X9
+		PSRAL	$14, X3
+		PSRAL	$14, X9
+		PACKSSLW	X9, X3
+		PADDW	X1, X3
+		PACKUSWB	X3, X2
+		MOVO	X2, X0
 		MOVOU	X0, (DI)
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	simdloop_21
-nosimdloop_23:
-		MOVQ	asmroll+-16(SP), CX
+		JNE	simdloop_53
+nosimdloop_54:
+		MOVQ	tail+-32(SP), CX
 		ORQ	CX, CX
-		JE	end_24
-asmloop_22:
-		MOVWQSX	(BX), DX
-		MOVBQZX	(SI)(DX*1), AX
-		MOVWQSX	(BP), DX
-		IMULQ	DX
-		MOVQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	1(SI)(DX*1), AX
-		MOVWQSX	2(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	2(SI)(DX*1), AX
-		MOVWQSX	4(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	3(SI)(DX*1), AX
-		MOVWQSX	6(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	4(SI)(DX*1), AX
-		MOVWQSX	8(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	5(SI)(DX*1), AX
-		MOVWQSX	10(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	6(SI)(DX*1), AX
-		MOVWQSX	12(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	7(SI)(DX*1), AX
-		MOVWQSX	14(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	8(SI)(DX*1), AX
-		MOVWQSX	16(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	9(SI)(DX*1), AX
-		MOVWQSX	18(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	10(SI)(DX*1), AX
-		MOVWQSX	20(BP), DX
-		IMULQ	DX
-		ADDQ	AX, sum+-40(SP)
-		MOVWQSX	(BX), DX
-		MOVBQZX	11(SI)(DX*1), AX
-		MOVWQSX	22(BP), DX
-		IMULQ	DX
-		ADDQ	$24, BP
-		ADDQ	sum+-40(SP), AX
-		ADDQ	$8192, AX
-		CMOVQLT	zero_0<>(SB), AX
-		SHRQ	$14, AX
-		CMPQ	u8max_2<>(SB), AX
-		CMOVQLT	u8max_2<>(SB), AX
-		ADDQ	$2, BX
-		MOVB	AL, (DI)
-		ADDQ	$1, DI
-		SUBQ	$1, CX
-		JNE	asmloop_22
-end_24:
-		MOVQ	srcref+-24(SP), SI
-		ADDQ	dstoff+-32(SP), DI
-		ADDQ	sp+128(FP), SI
-		MOVQ	SI, srcref+-24(SP)
-		SUBQ	$1, height+112(FP)
-		JNE	yloop_20
-		RET
-
-TEXT ·h8scaleNAmd64(SB),4,$64-136
-		MOVQ	dp+120(FP), BX
-		MOVQ	width+104(FP), CX
-		MOVQ	CX, DX
-		SUBQ	CX, BX
-		SHRQ	$4, CX
-		ANDQ	$15, DX
-		MOVQ	BX, dstoff+-32(SP)
-		MOVQ	CX, simdroll+-8(SP)
-		MOVQ	DX, asmroll+-16(SP)
-		MOVQ	src+24(FP), AX
-		MOVQ	AX, srcref+-24(SP)
-		MOVQ	taps+96(FP), DX
-		SUBQ	$2, DX
-		MOVQ	DX, inner+-64(SP)
-		PXOR	X15, X15
-		MOVO	hbits_1<>(SB), X14
-		MOVQ	src+24(FP), SI
-		MOVQ	dst+0(FP), DI
-yloop_25:
-		MOVQ	off+72(FP), BX
-		MOVQ	cof+48(FP), BP
-		MOVQ	simdroll+-8(SP), CX
-		ORQ	CX, CX
-		JE	nosimdloop_28
-simdloop_26:
+		JE	end_55
+		PCMPEQB	X8, X8
+		PXOR	X9, X9
+		PCMPEQB	X10, X10
+		PXOR	X11, X11
 		MOVWQSX	(BX), R8
 		MOVWQSX	2(BX), R9
 		MOVWQSX	4(BX), R10
@@ -1312,6 +2529,30 @@ simdloop_26:
 		PINSRW	$2, (SI)(R10*1), X3
 		PINSRW	$3, (SI)(R11*1), X3
 		ADDQ	$2, SI
+		MOVO	(BP), X12
+		PCMPGTW	ringthr_1<>(SB), X12
+		MOVO	16(BP), X13
+		PCMPGTW	ringthr_1<>(SB), X13
+		PACKSSWB	X13, X12
+		MOVO	X0, X13
+		PUNPCKLQDQ	X1, X13
+		PAND	X12, X13
+		PMAXUB	X13, X9
+		PXOR	ones_2<>(SB), X12
+		POR	X13, X12
+		PMINUB	X12, X8
+		MOVO	32(BP), X12
+		PCMPGTW	ringthr_1<>(SB), X12
+		MOVO	48(BP), X13
+		PCMPGTW	ringthr_1<>(SB), X13
+		PACKSSWB	X13, X12
+		MOVO	X2, X13
+		PUNPCKLQDQ	X3, X13
+		PAND	X12, X13
+		PMAXUB	X13, X11
+		PXOR	ones_2<>(SB), X12
+		POR	X13, X12
+		PMINUB	X12, X10
 		PUNPCKLBW	X15, X0
 		PMADDWL	(BP), X0
 		PUNPCKLBW	X15, X1
@@ -1321,9 +2562,11 @@ simdloop_26:
 		PUNPCKLBW	X15, X3
 		PMADDWL	48(BP), X3
 		ADDQ	$64, BP
-		MOVQ	DI, dstref+-48(SP)
+		MOVQ	DI, dstref+-56(SP)
 		MOVQ	inner+-64(SP), DI
-loop_30:
+		ORQ	DI, DI
+		JE	noloop_58
+loop_59:
 		MOVWQSX	(BX), R8
 		MOVWQSX	2(BX), R9
 		MOVWQSX	4(BX), R10
@@ -1357,6 +2600,30 @@ loop_30:
 		PINSRW	$2, (SI)(R10*1), X7
 		PINSRW	$3, (SI)(R11*1), X7
 		ADDQ	$2, SI
+		MOVO	(BP), X12
+		PCMPGTW	ringthr_1<>(SB), X12
+		MOVO	16(BP), X13
+		PCMPGTW	ringthr_1<>(SB), X13
+		PACKSSWB	X13, X12
+		MOVO	X4, X13
+		PUNPCKLQDQ	X5, X13
+		PAND	X12, X13
+		PMAXUB	X13, X9
+		PXOR	ones_2<>(SB), X12
+		POR	X13, X12
+		PMINUB	X12, X8
+		MOVO	32(BP), X12
+		PCMPGTW	ringthr_1<>(SB), X12
+		MOVO	48(BP), X13
+		PCMPGTW	ringthr_1<>(SB), X13
+		PACKSSWB	X13, X12
+		MOVO	X6, X13
+		PUNPCKLQDQ	X7, X13
+		PAND	X12, X13
+		PMAXUB	X13, X11
+		PXOR	ones_2<>(SB), X12
+		POR	X13, X12
+		PMINUB	X12, X10
 		PUNPCKLBW	X15, X4
 		PMADDWL	(BP), X4
 		PUNPCKLBW	X15, X5
@@ -1371,8 +2638,9 @@ loop_30:
 		PADDL	X6, X2
 		PADDL	X7, X3
 		SUBQ	$2, DI
-		JNE	loop_30
-		MOVQ	dstref+-48(SP), DI
+		JNE	loop_59
+noloop_58:
+		MOVQ	dstref+-56(SP), DI
 		MOVQ	taps+96(FP), AX
 		SUBQ	AX, SI
 		ADDQ	$32, BX
@@ -1387,54 +2655,104 @@ loop_30:
 		PACKSSLW	X1, X0
 		PACKSSLW	X3, X2
 		PACKUSWB	X2, X0
-		MOVOU	X0, (DI)
-		ADDQ	$16, DI
-		SUBQ	$1, CX
-		JNE	simdloop_26
-nosimdloop_28:
-		MOVQ	asmroll+-16(SP), CX
-		ORQ	CX, CX
-		JE	end_29
-asmloop_27:
-		MOVWQSX	(BX), DX
-		MOVBQZX	(SI)(DX*1), AX
-		MOVWQSX	(BP), DX
-		IMULQ	DX
-		MOVQ	AX, sum+-40(SP)
-		MOVQ	inner+-64(SP), AX
-		MOVQ	AX, count+-56(SP)
-loop_31:
-		MOVWQSX	(BX), DX
-		MOVBQZX	1(SI)(DX*1), AX
-		MOVWQSX	2(BP), DX
-		IMULQ	DX
-		ADDQ	$1, SI
-		ADDQ	$2, BP
-		ADDQ	AX, sum+-40(SP)
-		SUBQ	$1, count+-56(SP)
-		JNE	loop_31
-		MOVWQSX	(BX), DX
-		MOVBQZX	1(SI)(DX*1), AX
-		MOVWQSX	2(BP), DX
-		IMULQ	DX
-		ADDQ	$4, BP
-		SUBQ	inner+-64(SP), SI
-		ADDQ	sum+-40(SP), AX
-		ADDQ	$8192, AX
-		CMOVQLT	zero_0<>(SB), AX
-		SHRQ	$14, AX
-		CMPQ	u8max_2<>(SB), AX
-		CMOVQLT	u8max_2<>(SB), AX
-		ADDQ	$2, BX
-		MOVB	AL, (DI)
+		MOVO	X8, X12
+		PSRLW	$8, X12
+		PSLLW	$8, X8
+		PSRLW	$8, X8
+		PMINUB	X12, X8
+		MOVO	X10, X13
+		PSRLW	$8, X13
+		PSLLW	$8, X10
+		PSRLW	$8, X10
+		PMINUB	X13, X10
+		PACKUSWB	X10, X8
+		MOVO	X9, X12
+		PSRLW	$8, X12
+		PSLLW	$8, X9
+		PSRLW	$8, X9
+		PMAXUB	X12, X9
+		MOVO	X11, X13
+		PSRLW	$8, X13
+		PSLLW	$8, X11
+		PSRLW	$8, X11
+		PMAXUB	X13, X11
+		PACKUSWB	X11, X9
+		MOVO	X8, X1
+		PMINUB	X9, X1
+		PCMPEQB	X8, X1
+		PMAXUB	X0, X8
+		PMINUB	X9, X8
+		PAND	X1, X8
+		PANDN	X0, X1
+		POR	X1, X8
+		MOVOU	strength+-16(SP), X7
+		MOVO	X0, X1
+		PUNPCKLBW	X15, X1
+		MOVO	X8, X2
+		PUNPCKLBW	X15, X2
+		PSUBW	X1, X2
+		MOVO	X2, X3
+		PMULHW	X7, X3
+		PMULLW	X7, X2
+		MOVO	X2, X4
+		PUNPCKLWL	X3, X2
+		PUNPCKHWL	X3, X4
+		PADDL	X14, X2
+		PADDL	X14, X4
+		PSRAL	$14, X2
+		PSRAL	$14, X4
+		PACKSSLW	X4, X2
+		PADDW	X1, X2
+		MOVO	X0, X1
+		PUNPCKHBW	X15, X1
+		MOVO	X8, X3
+		PUNPCKHBW	X15, X3
+		PSUBW	X1, X3
+		MOVO	X3, X4
+		PMULHW	X7, X4
+		PMULLW	X7, X3
+		MOVO	X3, X9
+		PUNPCKLWL	X4, X3
+		PUNPCKHWL	X4, X9
+		PADDL	X14, X3
+		PADDL	X14, X9
+		PSRAL	$14, X3
+		PSRAL	$14, X9
+		PACKSSLW	X9, X3
+		PADDW	X1, X3
+		PACKUSWB	X3, X2
+		MOVO	X2, X0
+		TESTQ	$8, tail+-32(SP)
+		JE	skip8_60
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_60:
+		TESTQ	$4, tail+-32(SP)
+		JE	skip4_61
+		MOVQ	X0, AX
+		MOVL	AX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_61:
+		TESTQ	$2, tail+-32(SP)
+		JE	skip2_62
+		MOVQ	X0, AX
+		MOVW	AX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_62:
+		TESTQ	$1, tail+-32(SP)
+		JE	skip1_63
+		MOVQ	X0, AX
+		MOVB	AX, (DI)
 		ADDQ	$1, DI
-		SUBQ	$1, CX
-		JNE	asmloop_27
-end_29:
-		MOVQ	srcref+-24(SP), SI
-		ADDQ	dstoff+-32(SP), DI
+skip1_63:
+end_55:
+		MOVQ	srcref+-40(SP), SI
+		ADDQ	dstoff+-48(SP), DI
 		ADDQ	sp+128(FP), SI
-		MOVQ	SI, srcref+-24(SP)
+		MOVQ	SI, srcref+-40(SP)
 		SUBQ	$1, height+112(FP)
-		JNE	yloop_25
+		JNE	yloop_52
 		RET
@@ -11,8 +11,10 @@ Featuring:
  - YCbCr, RGBA, NRGBA & Gray resizes
  - YCbCr Chroma subsample ratio conversions
  - Optional interlaced-aware resizes
+ - Bob, blend & edge-directed deinterlacing
+ - One-to-many conversions sharing input passes
  - Parallel resizes
- - SIMD optimisations on AMD64
+ - SIMD optimisations on AMD64, bit-identical to pure Go
 
 The easiest way to use it is:
 
@@ -27,13 +29,25 @@ multiple images, the best way is:
         err := converter.Convert(output[i], input[i])
     }
 
-Note that by default, images are resized in parallel with GOMAXPROCS slices.
-Best performance is obtained when GOMAXPROCS is at least equal to your CPU
-count.
+Note that by default, images are resized in parallel with GOMAXPROCS slices,
+on a package-wide pool of GOMAXPROCS workers. Best performance is obtained
+when GOMAXPROCS is at least equal to your CPU count.
+
+Converters can share a Pool of persistent workers, set in ConverterConfig,
+which bounds the number of goroutines resizing at once across all of them,
+converting goroutines included.
+
+Progressive input converted to interlaced output is resampled into each field
+at its own line positions, with a vertical low-pass at the field rate so thin
+horizontal details do not flicker between fields.
+
+NewYCbCr, NewRGBA, NewNRGBA & NewGray allocate images with strides aligned
+for SIMD, and an ImagePool reuses such images across frames.
 */
 package rez
 
 import (
+	gocontext "context"
 	"fmt"
 	"image"
 	"runtime"
@@ -48,10 +62,21 @@ type Converter interface {
 	// dst = destination image
 	// src = source image
 	// Result is undefined if src points to the same data as dst
+	// Convert can be called concurrently from multiple goroutines
+	// Convert does not allocate after its first call
 	// Returns an error if the conversion fails
 	Convert(dst, src image.Image) error
 }
 
+// ContextConverter is implemented by converters able to stop early
+// Converters returned by NewConverter implement it
+type ContextConverter interface {
+	Converter
+	// Converts like Convert, but stops as soon as possible once ctx is done
+	// Returns ctx.Err() if ctx is done, dst content is undefined then
+	ConvertContext(ctx gocontext.Context, dst, src image.Image) error
+}
+
 // ChromaRatio is a chroma subsampling ratio
 type ChromaRatio int
 
@@ -68,6 +93,28 @@ const (
 	Ratio444
 )
 
+// FieldOrder is the temporal order of fields in an interlaced image
+type FieldOrder int
+
+const (
+	// TopFieldFirst means even lines are displayed first
+	TopFieldFirst FieldOrder = iota
+	// BottomFieldFirst means odd lines are displayed first
+	BottomFieldFirst
+)
+
+// FieldSelect selects which fields of an interlaced image are accessed
+type FieldSelect int
+
+const (
+	// BothFields accesses the whole interlaced frame
+	BothFields FieldSelect = iota
+	// TopField accesses only even lines as a half-height progressive image
+	TopField
+	// BottomField accesses only odd lines as a half-height progressive image
+	BottomField
+)
+
 // Descriptor describes an image properties
 type Descriptor struct {
 	Width      int         // width in pixels
@@ -76,6 +123,8 @@ type Descriptor struct {
 	Pack       int         // pixels per pack
 	Interlaced bool        // progressive or interlaced
 	Planes     int         // number of planes
+	Order      FieldOrder  // field order if interlaced
+	Field      FieldSelect // field(s) to access if interlaced
 }
 
 // Check returns whether the descriptor is valid
@@ -83,6 +132,15 @@ func (d *Descriptor) Check() error {
 	if d.Pack < 1 || d.Pack > 4 {
 		return fmt.Errorf("invalid pack value %v", d.Pack)
 	}
+	if d.Order != TopFieldFirst && d.Order != BottomFieldFirst {
+		return fmt.Errorf("invalid field order %v", d.Order)
+	}
+	if d.Field < BothFields || d.Field > BottomField {
+		return fmt.Errorf("invalid field selection %v", d.Field)
+	}
+	if d.Field != BothFields && !d.Interlaced {
+		return fmt.Errorf("unable to select a field of a progressive image")
+	}
 	for i := 0; i < d.Planes; i++ {
 		h := d.GetHeight(i)
 		if d.Interlaced && h%2 != 0 && h != d.Height {
@@ -132,12 +190,67 @@ func (d *Descriptor) GetHeight(plane int) int {
 	panic(fmt.Errorf("invalid ratio %v", d.Ratio))
 }
 
+// interlacedFrame returns whether planes are accessed as interlaced frames
+func (d *Descriptor) interlacedFrame() bool {
+	return d.Interlaced && d.Field == BothFields
+}
+
+// fieldHeight returns the accessed height in pixels for the input plane
+func (d *Descriptor) fieldHeight(plane int) int {
+	h := d.GetHeight(plane)
+	switch d.Field {
+	case TopField:
+		return (h + 1) >> 1
+	case BottomField:
+		return h >> 1
+	}
+	return h
+}
+
+// PlaneFilters are filters used to resize a single plane
+// Nil filters default to the filter given to NewConverter
+type PlaneFilters struct {
+	Horizontal Filter // filter used for horizontal resizes
+	Vertical   Filter // filter used for vertical resizes
+}
+
 // ConverterConfig is a configuration used with NewConverter
 type ConverterConfig struct {
-	Input      Descriptor // input description
-	Output     Descriptor // output description
-	Threads    int        // number of allowed "threads"
-	DisableAsm bool       // disable asm optimisations
+	Input       Descriptor              // input description
+	Output      Descriptor              // output description
+	Threads     int                     // number of allowed "threads"
+	DisableAsm  bool                    // disable asm optimisations
+	Deinterlace DeinterlaceMode         // interlaced to progressive algorithm
+	Filters     [maxPlanes]PlaneFilters // optional per-plane filters
+	AntiRinging float64                 // anti-ringing strength in [0, 1]
+	Sharpen     Sharpen                 // optional unsharp mask
+	// MaxFilterRatio enables 2:1 box reductions before the final filter pass
+	// on downscales above this ratio, see ResizerConfig [default=0=disabled]
+	MaxFilterRatio float64
+	// StripRows enables strip-mined resizes, where vertical & horizontal
+	// passes run on strips of StripRows intermediate rows instead of a full
+	// intermediate plane, on vertical-first plans only [default=0=disabled]
+	StripRows int
+	// Verify converts every image a second time with Go scalers only, and
+	// makes Convert fail on the first pixel which differs from asm scalers
+	// It is ignored without asm scalers [default=false]
+	Verify bool
+	// Pool runs parallel jobs when set, instead of the default pool
+	// A single Pool can be shared by many converters, which then wait for
+	// free pool slots before converting [default=nil]
+	Pool *Pool
+}
+
+// getFilters returns horizontal & vertical filters for the input plane
+func (c *ConverterConfig) getFilters(plane int, filter Filter) (Filter, Filter) {
+	h, v := c.Filters[plane].Horizontal, c.Filters[plane].Vertical
+	if h == nil {
+		h = filter
+	}
+	if v == nil {
+		v = filter
+	}
+	return h, v
 }
 
 const (
@@ -157,7 +270,16 @@ type converterContext struct {
 	ConverterConfig
 	wrez   [maxPlanes]Resizer
 	hrez   [maxPlanes]Resizer
-	buffer [maxPlanes]*Plane
+	buffer [maxPlanes]*Plane // intermediate plane templates
+	dint   [maxPlanes]*deinterlacer
+	dbuf   [maxPlanes]*Plane // deinterlaced plane templates
+	sharp  [maxPlanes]*sharpener
+	strip  [maxPlanes]*striper
+	plan   [maxPlanes]PlanePlan
+	// scratch holds intermediate planes, so concurrent conversions do not
+	// share any buffer
+	scratch freeList
+	verify  *verifier // optional Go only converter, see Verify
 }
 
 func toInterlacedString(interlaced bool) string {
@@ -167,6 +289,13 @@ func toInterlacedString(interlaced bool) string {
 	return "progressive"
 }
 
+func toFieldOrderString(order FieldOrder) string {
+	if order == BottomFieldFirst {
+		return "bottom-field-first"
+	}
+	return "top-field-first"
+}
+
 func toPackedString(pack int) string {
 	return fmt.Sprintf("%v-packed", pack)
 }
@@ -182,10 +311,14 @@ func checkConversion(dst, src *Descriptor) error {
 	if err := dst.Check(); err != nil {
 		return fmt.Errorf("invalid output format: %v", err)
 	}
-	if src.Interlaced != dst.Interlaced {
+	if src.interlacedFrame() && dst.interlacedFrame() && src.Order != dst.Order {
 		return fmt.Errorf("unable to convert %v input to %v output",
-			toInterlacedString(src.Interlaced),
-			toInterlacedString(dst.Interlaced))
+			toFieldOrderString(src.Order),
+			toFieldOrderString(dst.Order))
+	}
+	if src.interlacedFrame() && dst.Interlaced && dst.Field != BothFields {
+		// both input fields would be blended into a single output field
+		return fmt.Errorf("unable to convert interlaced frame input to a single field output, select an input field")
 	}
 	if src.Pack != dst.Pack {
 		return fmt.Errorf("unable to convert %v input to %v output",
@@ -208,26 +341,60 @@ func min(a, b int) int {
 
 // NewConverter returns a Converter interface
 // cfg = converter configuration
-// filter = filter used for resizing
+// filter = filter used for resizing planes without cfg.Filters
 // Returns an error if the conversion is invalid or not implemented
 func NewConverter(cfg *ConverterConfig, filter Filter) (Converter, error) {
+	ctx, err := newConverterContext(cfg, filter)
+	if err != nil {
+		return nil, err
+	}
+	ctx.allocate()
+	if cfg.Verify && hasAsm() && !cfg.DisableAsm {
+		ctx.verify, err = newVerifier(cfg, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ctx, nil
+}
+
+// newConverterContext prepares every plane resizer without allocating
+// intermediate buffers
+func newConverterContext(cfg *ConverterConfig, filter Filter) (*converterContext, error) {
 	err := checkConversion(&cfg.Output, &cfg.Input)
 	if err != nil {
 		return nil, err
 	}
+	err = checkDeinterlaceMode(cfg.Deinterlace)
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.Sharpen.check()
+	if err != nil {
+		return nil, err
+	}
 	if cfg.Threads == 0 {
 		cfg.Threads = runtime.GOMAXPROCS(0)
 	}
+	err = cfg.Pool.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer cfg.Pool.leave()
 	ctx := &converterContext{
 		ConverterConfig: *cfg,
 	}
-	size := 0
+	// progressive input is split into both fields of interlaced output,
+	// and interlaced input is deinterlaced before progressive output
+	interlaced := cfg.Output.interlacedFrame()
+	split := !cfg.Input.interlacedFrame() && interlaced
+	deinterlace := cfg.Input.interlacedFrame() && !cfg.Output.Interlaced
 	group := sync.WaitGroup{}
 	for i := 0; i < cfg.Output.Planes; i++ {
 		win := cfg.Input.GetWidth(i)
-		hin := cfg.Input.GetHeight(i)
+		hin := cfg.Input.fieldHeight(i)
 		wout := cfg.Output.GetWidth(i)
-		hout := cfg.Output.GetHeight(i)
+		hout := cfg.Output.fieldHeight(i)
 		if win < 2 || hin < 2 {
 			return nil, fmt.Errorf("input size too small %vx%v", win, hin)
 		}
@@ -235,68 +402,114 @@ func NewConverter(cfg *ConverterConfig, filter Filter) (Converter, error) {
 			return nil, fmt.Errorf("output size too small %vx%v", wout, hout)
 		}
 		idx := i
+		hfilter, vfilter := cfg.getFilters(i, filter)
 		if win != wout {
-			dispatch(&group, cfg.Threads, func() {
-				threads := min(cfg.Threads, hout)
+			dispatch(cfg.Pool, &group, cfg.Threads, taskFunc(func() {
 				ctx.wrez[idx] = NewResize(&ResizerConfig{
-					Depth:      8,
-					Input:      win,
-					Output:     wout,
-					Vertical:   false,
-					Interlaced: false,
-					Pack:       cfg.Input.Pack,
-					Threads:    threads,
-					DisableAsm: cfg.DisableAsm || wout < 16,
-				}, filter)
-			})
+					Depth:          8,
+					Input:          win,
+					Output:         wout,
+					Vertical:       false,
+					Interlaced:     false,
+					Pack:           cfg.Input.Pack,
+					Threads:        cfg.Threads,
+					DisableAsm:     cfg.DisableAsm,
+					AntiRinging:    cfg.AntiRinging,
+					MaxFilterRatio: cfg.MaxFilterRatio,
+					Pool:           cfg.Pool,
+				}, hfilter)
+			}))
 		}
-		if hin != hout {
-			dispatch(&group, cfg.Threads, func() {
-				threads := min(cfg.Threads, hout)
-				if cfg.Output.Interlaced {
-					threads = min(cfg.Threads, hout>>1)
-				}
+		if hin != hout || split {
+			dispatch(cfg.Pool, &group, cfg.Threads, taskFunc(func() {
 				ctx.hrez[idx] = NewResize(&ResizerConfig{
-					Depth:      8,
-					Input:      hin,
-					Output:     hout,
-					Vertical:   true,
-					Interlaced: cfg.Output.Interlaced,
-					Pack:       cfg.Output.Pack,
-					Threads:    threads,
-					DisableAsm: cfg.DisableAsm || wout < 16 || win < 16,
-				}, filter)
-			})
+					Depth:          8,
+					Input:          hin,
+					Output:         hout,
+					Vertical:       true,
+					Interlaced:     interlaced,
+					split:          split,
+					Pack:           cfg.Output.Pack,
+					Threads:        cfg.Threads,
+					DisableAsm:     cfg.DisableAsm,
+					AntiRinging:    cfg.AntiRinging,
+					MaxFilterRatio: cfg.MaxFilterRatio,
+					Pool:           cfg.Pool,
+				}, vfilter)
+			}))
+		}
+		if cfg.Sharpen.Amount > 0 {
+			dispatch(cfg.Pool, &group, cfg.Threads, taskFunc(func() {
+				ctx.sharp[idx] = newSharpener(&cfg.Sharpen, wout, hout,
+					cfg.Output.Pack, cfg.Threads, cfg.Output.interlacedFrame(),
+					cfg.DisableAsm, cfg.Pool)
+			}))
 		}
-		if win != wout && hin != hout {
+		if deinterlace {
+			dispatch(cfg.Pool, &group, cfg.Threads, taskFunc(func() {
+				ctx.dint[idx] = newDeinterlacer(cfg.Deinterlace, cfg.Input.Order, win, hin,
+					cfg.Input.Pack, cfg.Threads, cfg.DisableAsm, cfg.Pool, vfilter)
+			}))
 			p := &Plane{
 				Width:  win,
-				Height: hout,
+				Height: hin,
 				Pitch:  align(win*cfg.Input.Pack, 16),
 				Pack:   cfg.Input.Pack,
 			}
-			size += p.Pitch * p.Height
-			ctx.buffer[i] = p
+			ctx.dbuf[i] = p
 		}
 	}
-	if size != 0 {
-		buffer := make([]byte, size)
-		idx := 0
-		for i := 0; i < cfg.Output.Planes; i++ {
-			if p := ctx.buffer[i]; p != nil {
-				size := p.Pitch*(p.Height-1) + p.Width*p.Pack
-				p.Data = buffer[idx : idx+size]
-				idx += p.Pitch * p.Height
+	group.Wait()
+	for i := 0; i < cfg.Output.Planes; i++ {
+		win := cfg.Input.GetWidth(i)
+		hin := cfg.Input.fieldHeight(i)
+		hout := cfg.Output.fieldHeight(i)
+		plan := getPlan(ctx.hrez[i], ctx.wrez[i], win, hin, cfg.Output.GetWidth(i), hout)
+		if plan.Width != 0 && plan.Order == VerticalFirst && cfg.StripRows > 0 {
+			// strips chain vertical then horizontal passes
+			ctx.strip[i] = newStriper(ctx.hrez[i], ctx.wrez[i], cfg.StripRows,
+				win, hout, cfg.Input.Pack, cfg.Threads, cfg.Pool)
+			if ctx.strip[i] != nil {
+				plan.Width, plan.Height, plan.Strips = 0, 0, true
+			}
+		}
+		if plan.Width != 0 {
+			ctx.buffer[i] = &Plane{
+				Width:  plan.Width,
+				Height: plan.Height,
+				Pitch:  align(plan.Width*cfg.Input.Pack, 16),
+				Pack:   cfg.Input.Pack,
 			}
 		}
+		ctx.plan[i] = plan
+		if ctx.sharp[i] != nil {
+			ctx.sharp[i].attach(ctx.getLastPass(i), ctx.strip[i])
+		}
 	}
-	group.Wait()
 	return ctx, nil
 }
 
+// allocate prepares intermediate planes for one conversion
+func (ctx *converterContext) allocate() {
+	n := ctx.Output.Planes
+	ctx.scratch.alloc = func() interface{} {
+		return newScratch(append(ctx.buffer[:n:n], ctx.dbuf[:n]...))
+	}
+	ctx.scratch.put(ctx.scratch.get())
+}
+
+// release returns s to the free list, without references to user images
+func (ctx *converterContext) release(s *scratch) {
+	s.src, s.dst = [maxPlanes]Plane{}, [maxPlanes]Plane{}
+	s.jobs = [maxPlanes]planeJob{}
+	ctx.scratch.put(s)
+}
+
 // GetRatio returns a ChromaRatio from an image.YCbCrSubsampleRatio
 func GetRatio(value image.YCbCrSubsampleRatio) ChromaRatio {
 	switch value {
+	case image.YCbCrSubsampleRatio411:
+		return Ratio411
 	case image.YCbCrSubsampleRatio420:
 		return Ratio420
 	case image.YCbCrSubsampleRatio422:
@@ -310,21 +523,35 @@ func GetRatio(value image.YCbCrSubsampleRatio) ChromaRatio {
 }
 
 func inspect(data image.Image, interlaced bool) (*Descriptor, []Plane, error) {
+	d := &Descriptor{}
+	planes, err := inspectTo(d, make([]Plane, maxPlanes), data, interlaced)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, planes, nil
+}
+
+// inspectTo is inspect without allocations, filling d & planes
+// planes must hold at least maxPlanes planes
+func inspectTo(d *Descriptor, planes []Plane, data image.Image, interlaced bool) ([]Plane, error) {
 	switch t := data.(type) {
 	case *image.YCbCr:
-		d, p := inspectYuv(t, interlaced)
-		return d, p, nil
+		*d = getYuvDescriptor(t, interlaced)
+		if r, _ := getSubsampleRatio(d.Ratio); r != t.SubsampleRatio {
+			return nil, fmt.Errorf("unsupported subsample ratio %v", t.SubsampleRatio)
+		}
+		return getYuvPlanes(t, d, planes), nil
 	case *image.RGBA:
-		d, p := inspectRgba(t, interlaced)
-		return d, p, nil
+		*d = getRgbDescriptor(t.Rect, interlaced)
+		return getRgbaPlane(t, d, planes), nil
 	case *image.NRGBA:
-		d, p := inspectNrgba(t, interlaced)
-		return d, p, nil
+		*d = getRgbDescriptor(t.Rect, interlaced)
+		return getNrgbaPlane(t, d, planes), nil
 	case *image.Gray:
-		d, p := inspectGray(t, interlaced)
-		return d, p, nil
+		*d = getGrayDescriptor(t, interlaced)
+		return getGrayPlane(t, d, planes), nil
 	}
-	return nil, nil, fmt.Errorf("unknown image format")
+	return nil, fmt.Errorf("unknown image format")
 }
 
 func getYuvDescriptor(img *image.YCbCr, interlaced bool) Descriptor {
@@ -366,8 +593,7 @@ func setPlane(p *Plane, rect image.Rectangle, offset func(x, y int) int, pix []b
 	p.Data = pix[base : base+p.Pitch*(p.Height-1)+p.Width*p.Pack]
 }
 
-func getYuvPlanes(img *image.YCbCr, d *Descriptor) []Plane {
-	planes := []Plane{}
+func getYuvPlanes(img *image.YCbCr, d *Descriptor, planes []Plane) []Plane {
 	for i := 0; i < maxPlanes; i++ {
 		p := Plane{
 			Width:  d.GetWidth(i),
@@ -385,12 +611,12 @@ func getYuvPlanes(img *image.YCbCr, d *Descriptor) []Plane {
 			p.Pitch = img.CStride
 			setPlane(&p, img.Rect, img.COffset, img.Cr)
 		}
-		planes = append(planes, p)
+		planes[i] = p
 	}
-	return planes
+	return planes[:maxPlanes]
 }
 
-func getSinglePlane(d *Descriptor, pitch int, rect image.Rectangle, offset func(x, y int) int, pix []byte) []Plane {
+func getSinglePlane(d *Descriptor, pitch int, rect image.Rectangle, offset func(x, y int) int, pix []byte, planes []Plane) []Plane {
 	p := Plane{
 		Width:  d.Width,
 		Height: d.Height,
@@ -398,82 +624,164 @@ func getSinglePlane(d *Descriptor, pitch int, rect image.Rectangle, offset func(
 		Pitch:  pitch,
 	}
 	setPlane(&p, rect, offset, pix)
-	return []Plane{p}
+	planes[0] = p
+	return planes[:1]
 }
 
-func getRgbaPlane(img *image.RGBA, d *Descriptor) []Plane {
-	return getSinglePlane(d, img.Stride, img.Rect, img.PixOffset, img.Pix)
+func getRgbaPlane(img *image.RGBA, d *Descriptor, planes []Plane) []Plane {
+	return getSinglePlane(d, img.Stride, img.Rect, img.PixOffset, img.Pix, planes)
 }
 
-func getNrgbaPlane(img *image.NRGBA, d *Descriptor) []Plane {
-	return getSinglePlane(d, img.Stride, img.Rect, img.PixOffset, img.Pix)
+func getNrgbaPlane(img *image.NRGBA, d *Descriptor, planes []Plane) []Plane {
+	return getSinglePlane(d, img.Stride, img.Rect, img.PixOffset, img.Pix, planes)
 }
 
-func getGrayPlane(img *image.Gray, d *Descriptor) []Plane {
-	return getSinglePlane(d, img.Stride, img.Rect, img.PixOffset, img.Pix)
+func getGrayPlane(img *image.Gray, d *Descriptor, planes []Plane) []Plane {
+	return getSinglePlane(d, img.Stride, img.Rect, img.PixOffset, img.Pix, planes)
 }
 
-func inspectYuv(img *image.YCbCr, interlaced bool) (*Descriptor, []Plane) {
-	d := getYuvDescriptor(img, interlaced)
-	return &d, getYuvPlanes(img, &d)
+// selectField restricts planes to the field selected in the descriptor
+func selectField(planes []Plane, d *Descriptor) {
+	if d.Field == BothFields {
+		return
+	}
+	for i := range planes {
+		p := &planes[i]
+		h := d.fieldHeight(i)
+		if d.Field == BottomField {
+			p.Data = p.Data[p.Pitch:]
+		}
+		p.Pitch *= 2
+		p.Height = h
+		p.Data = p.Data[:p.Pitch*(h-1)+p.Width*p.Pack]
+	}
 }
 
-func inspectRgba(img *image.RGBA, interlaced bool) (*Descriptor, []Plane) {
-	d := getRgbDescriptor(img.Rect, interlaced)
-	return &d, getRgbaPlane(img, &d)
+// planeJob runs every pass of a plane
+type planeJob struct {
+	done   <-chan struct{}
+	dst    *Plane
+	src    *Plane
+	buf    *Plane
+	first  Resizer
+	second Resizer
+	dint   *deinterlacer
+	dbuf   *Plane
+	sharp  *sharpener
+	strip  *striper
 }
 
-func inspectNrgba(img *image.NRGBA, interlaced bool) (*Descriptor, []Plane) {
-	d := getRgbDescriptor(img.Rect, interlaced)
-	return &d, getNrgbaPlane(img, &d)
+func (j *planeJob) run() {
+	convertPlane(j.done, j.dst, j.src, j.buf, j.first, j.second, j.dint, j.dbuf, j.sharp, j.strip)
 }
 
-func inspectGray(img *image.Gray, interlaced bool) (*Descriptor, []Plane) {
-	d := getGrayDescriptor(img, interlaced)
-	return &d, getGrayPlane(img, &d)
+// convertPlane runs first & second resize passes from src to dst, through
+// buf when both passes are needed
+// Passes stop early once done is closed
+func convertPlane(done <-chan struct{}, dst, src, buf *Plane, first, second Resizer, dint *deinterlacer, dbuf *Plane, sharp *sharpener, strip *striper) {
+	if dint != nil {
+		dint.Deinterlace(dbuf, src, done)
+		src = dbuf
+	}
+	last := second
+	if strip != nil {
+		last = nil
+	} else if first != nil && second != nil {
+		resizeWith(done, first, buf.Data, src.Data, src.Width, src.Height, buf.Pitch, src.Pitch)
+		src = buf
+	} else if second == nil {
+		last = first
+	}
+	if !canceled(done) {
+		resizeLast(done, dst, src, last, strip, sharp)
+	}
 }
 
-func resizePlane(group *sync.WaitGroup, threads int, dst, src, buf *Plane, hrez, wrez Resizer) {
-	dispatch(group, threads, func() {
-		hdst := dst
-		wsrc := src
-		if hrez != nil && wrez != nil {
-			hdst = buf
-			wsrc = buf
-		}
-		if hrez != nil {
-			hrez.Resize(hdst.Data, src.Data, src.Width, src.Height, hdst.Pitch, src.Pitch)
-		}
-		if wrez != nil {
-			wrez.Resize(dst.Data, wsrc.Data, wsrc.Width, wsrc.Height, dst.Pitch, wsrc.Pitch)
-		}
-		if hrez == nil && wrez == nil {
-			copyPlane(dst.Data, src.Data, src.Width*src.Pack, src.Height, dst.Pitch, src.Pitch)
-		}
-	})
+// resizeLast runs the last pass of a plane from src into dst, with strips, a
+// resizer or a copy when last is nil, sharpening rows on the way if needed
+func resizeLast(done <-chan struct{}, dst, src *Plane, last Resizer, strip *striper, sharp *sharpener) {
+	switch {
+	case sharp != nil:
+		sharp.Sharpen(dst, src, done)
+	case strip != nil:
+		strip.Resize(dst, src, done)
+	case last != nil:
+		resizeWith(done, last, dst.Data, src.Data, src.Width, src.Height, dst.Pitch, src.Pitch)
+	default:
+		copyPlane(dst.Data, src.Data, src.Width*src.Pack, src.Height, dst.Pitch, src.Pitch)
+	}
 }
 
 func (ctx *converterContext) Convert(output, input image.Image) error {
-	id, src, err := inspect(input, ctx.Input.Interlaced)
+	return ctx.convert(nil, output, input)
+}
+
+func (ctx *converterContext) ConvertContext(c gocontext.Context, output, input image.Image) error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	if err := ctx.convert(c.Done(), output, input); err != nil {
+		return err
+	}
+	return c.Err()
+}
+
+// convert converts input into output, stopping early once done is closed
+func (ctx *converterContext) convert(done <-chan struct{}, output, input image.Image) error {
+	err := ctx.Pool.enter()
 	if err != nil {
 		return err
 	}
-	od, dst, err := inspect(output, ctx.Output.Interlaced)
+	defer ctx.Pool.leave()
+	s := ctx.scratch.get().(*scratch)
+	defer ctx.release(s)
+	id, od := Descriptor{}, Descriptor{}
+	src, err := inspectTo(&id, s.src[:], input, ctx.Input.Interlaced)
 	if err != nil {
 		return err
 	}
-	err = checkConversion(od, id)
+	dst, err := inspectTo(&od, s.dst[:], output, ctx.Output.Interlaced)
 	if err != nil {
 		return err
 	}
-	group := sync.WaitGroup{}
-	for i := 0; i < ctx.Input.Planes; i++ {
-		resizePlane(&group, ctx.Threads, &dst[i], &src[i], ctx.buffer[i], ctx.hrez[i], ctx.wrez[i])
+	id.Order, id.Field = ctx.Input.Order, ctx.Input.Field
+	od.Order, od.Field = ctx.Output.Order, ctx.Output.Field
+	err = checkConversion(&od, &id)
+	if err != nil {
+		return err
+	}
+	selectField(src, &id)
+	selectField(dst, &od)
+	ctx.convertPlanes(done, s, dst, src)
+	if ctx.verify != nil && !canceled(done) {
+		return ctx.verify.check(done, dst, src)
 	}
-	group.Wait()
 	return nil
 }
 
+// convertPlanes resizes src planes into dst planes with s intermediate planes
+func (ctx *converterContext) convertPlanes(done <-chan struct{}, s *scratch, dst, src []Plane) {
+	n := ctx.Output.Planes
+	for i := 0; i < ctx.Input.Planes; i++ {
+		first, second := ctx.getPasses(i)
+		j := &s.jobs[i]
+		*j = planeJob{
+			done:   done,
+			dst:    &dst[i],
+			src:    &src[i],
+			buf:    s.planes[i],
+			first:  first,
+			second: second,
+			dint:   ctx.dint[i],
+			dbuf:   s.planes[n+i],
+			sharp:  ctx.sharp[i],
+			strip:  ctx.strip[i],
+		}
+		dispatch(ctx.Pool, &s.group, ctx.Threads, j)
+	}
+	s.group.Wait()
+}
+
 // PrepareConversion returns a ConverterConfig properly set for a conversion
 // from input images to output images
 // Returns an error if the conversion is not possible
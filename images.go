@@ -0,0 +1,173 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+import (
+	"fmt"
+	"image"
+	"sync"
+)
+
+const (
+	// imageAlign is the stride alignment in bytes of allocated images
+	imageAlign = 16
+)
+
+// getPlaneSize returns the aligned pitch & padded size of a plane
+func getPlaneSize(width, height int) (int, int) {
+	pitch := align(width, imageAlign)
+	return pitch, pitch * height
+}
+
+// newYCbCr allocates a ycbcr image with ch chroma rows, from a single buffer
+func newYCbCr(r image.Rectangle, ratio image.YCbCrSubsampleRatio, ch int) *image.YCbCr {
+	w, h := r.Dx(), r.Dy()
+	cw := w
+	switch ratio {
+	case image.YCbCrSubsampleRatio422, image.YCbCrSubsampleRatio420:
+		cw = (r.Max.X+1)/2 - r.Min.X/2
+	case image.YCbCrSubsampleRatio411, image.YCbCrSubsampleRatio410:
+		cw = (r.Max.X+3)/4 - r.Min.X/4
+	}
+	ypitch, ysize := getPlaneSize(w, h)
+	cpitch, csize := getPlaneSize(cw, ch)
+	buffer := make([]byte, ysize+2*csize)
+	return &image.YCbCr{
+		Y:              buffer[:ysize:ysize],
+		Cb:             buffer[ysize : ysize+csize : ysize+csize],
+		Cr:             buffer[ysize+csize:],
+		YStride:        ypitch,
+		CStride:        cpitch,
+		SubsampleRatio: ratio,
+		Rect:           r,
+	}
+}
+
+// getChromaHeight returns the number of chroma rows of a ycbcr image
+func getChromaHeight(r image.Rectangle, ratio image.YCbCrSubsampleRatio) int {
+	switch ratio {
+	case image.YCbCrSubsampleRatio420, image.YCbCrSubsampleRatio440, image.YCbCrSubsampleRatio410:
+		return (r.Max.Y+1)/2 - r.Min.Y/2
+	}
+	return r.Dy()
+}
+
+// NewYCbCr returns a new ycbcr image like image.NewYCbCr, with strides
+// aligned for SIMD and every row padded up to its stride
+func NewYCbCr(r image.Rectangle, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	return newYCbCr(r, ratio, getChromaHeight(r, ratio))
+}
+
+// NewRGBA returns a new rgba image like image.NewRGBA, with an aligned stride
+// and every row padded up to its stride
+func NewRGBA(r image.Rectangle) *image.RGBA {
+	pitch, size := getPlaneSize(r.Dx()*4, r.Dy())
+	return &image.RGBA{Pix: make([]byte, size), Stride: pitch, Rect: r}
+}
+
+// NewNRGBA returns a new nrgba image like image.NewNRGBA, with an aligned
+// stride and every row padded up to its stride
+func NewNRGBA(r image.Rectangle) *image.NRGBA {
+	pitch, size := getPlaneSize(r.Dx()*4, r.Dy())
+	return &image.NRGBA{Pix: make([]byte, size), Stride: pitch, Rect: r}
+}
+
+// NewGray returns a new gray image like image.NewGray, with an aligned
+// stride and every row padded up to its stride
+func NewGray(r image.Rectangle) *image.Gray {
+	pitch, size := getPlaneSize(r.Dx(), r.Dy())
+	return &image.Gray{Pix: make([]byte, size), Stride: pitch, Rect: r}
+}
+
+// getSubsampleRatio returns an image.YCbCrSubsampleRatio from a ChromaRatio
+func getSubsampleRatio(value ChromaRatio) (image.YCbCrSubsampleRatio, error) {
+	switch value {
+	case Ratio411:
+		return image.YCbCrSubsampleRatio411, nil
+	case Ratio420:
+		return image.YCbCrSubsampleRatio420, nil
+	case Ratio422:
+		return image.YCbCrSubsampleRatio422, nil
+	case Ratio440:
+		return image.YCbCrSubsampleRatio440, nil
+	case Ratio444:
+		return image.YCbCrSubsampleRatio444, nil
+	}
+	return 0, fmt.Errorf("invalid ratio %v", value)
+}
+
+// NewImage returns a new image with aligned strides matching d
+// Packed descriptors get *image.RGBA images, as NRGBA shares their layout
+// Returns an error if no image format matches d
+func NewImage(d *Descriptor) (image.Image, error) {
+	if err := d.Check(); err != nil {
+		return nil, err
+	}
+	r := image.Rect(0, 0, d.Width, d.Height)
+	switch {
+	case d.Planes == 3 && d.Pack == 1:
+		ratio, err := getSubsampleRatio(d.Ratio)
+		if err != nil {
+			return nil, err
+		}
+		// interlaced descriptors may need one more chroma row
+		ch := max(getChromaHeight(r, ratio), d.GetHeight(1))
+		return newYCbCr(r, ratio, ch), nil
+	case d.Planes == 1 && d.Pack == 4:
+		return NewRGBA(r), nil
+	case d.Planes == 1 && d.Pack == 1:
+		return NewGray(r), nil
+	}
+	return nil, fmt.Errorf("unsupported format %v planes with pack %v", d.Planes, d.Pack)
+}
+
+// ImagePool keeps images allocated with NewImage for reuse across frames
+// It is safe for concurrent use
+type ImagePool struct {
+	lock   sync.Mutex
+	images map[Descriptor][]image.Image
+}
+
+// NewImagePool returns an empty ImagePool
+func NewImagePool() *ImagePool {
+	return &ImagePool{
+		images: map[Descriptor][]image.Image{},
+	}
+}
+
+// getPoolKey returns the pool key of d, ignoring fields without effect on
+// image layouts
+func getPoolKey(d *Descriptor) Descriptor {
+	key := *d
+	key.Order = TopFieldFirst
+	key.Field = BothFields
+	return key
+}
+
+// Get returns a released image matching d, or a new image from NewImage
+// Image content is undefined
+func (p *ImagePool) Get(d *Descriptor) (image.Image, error) {
+	key := getPoolKey(d)
+	p.lock.Lock()
+	list := p.images[key]
+	if n := len(list); n > 0 {
+		img := list[n-1]
+		list[n-1] = nil
+		p.images[key] = list[:n-1]
+		p.lock.Unlock()
+		return img, nil
+	}
+	p.lock.Unlock()
+	return NewImage(d)
+}
+
+// Put releases img, previously returned by Get with d, for later calls
+// img must not be used after Put
+func (p *ImagePool) Put(d *Descriptor, img image.Image) {
+	key := getPoolKey(d)
+	p.lock.Lock()
+	p.images[key] = append(p.images[key], img)
+	p.lock.Unlock()
+}
@@ -13,7 +13,8 @@ type kernel struct {
 	coeffs   []int16
 	offsets  []int16
 	size     int
-	cofscale int // how many more coeffs do we have
+	cofscale int     // how many more coeffs do we have
+	raw      []int16 // coeffs before any simd reordering
 }
 
 func bin(v bool) uint {
@@ -40,10 +41,34 @@ func max(a, b int) int {
 	return b
 }
 
+// getFilterStep returns the filter scale per input pixel
+// Filters widen on downscales, and split kernels filter at the field rate
+func getFilterStep(cfg *ResizerConfig, filter Filter) float64 {
+	if _, point := filter.(pointSampler); point || cfg.shift != 0 {
+		return 1
+	}
+	return math.Min(1, float64(cfg.Output)/float64(cfg.Input<<bin(cfg.split)))
+}
+
+// getTaps returns the number of filter taps per output pixel, without simd
+// padding
+func getTaps(cfg *ResizerConfig, filter Filter) int {
+	field := bin(cfg.Vertical && cfg.Interlaced && !cfg.split)
+	support := getSupport(filter) / getFilterStep(cfg, filter)
+	return min(int(math.Ceil(support))*2, (cfg.Input>>field)&^1)
+}
+
+// makeDoubleKernel returns floating point weights of output field idx
+// field = whether input is read one field at a time
 func makeDoubleKernel(cfg *ResizerConfig, filter Filter, field, idx uint) ([]int16, []float64, []float64, int, int) {
 	scale := float64(cfg.Output) / float64(cfg.Input)
-	step := math.Min(1, scale)
-	support := float64(filter.Taps()) / step
+	xmid := float64(cfg.Input-cfg.Output) / float64(cfg.Output*2)
+	if cfg.shift != 0 {
+		scale, xmid = 1, cfg.shift
+	}
+	step := getFilterStep(cfg, filter)
+	_, point := filter.(pointSampler)
+	support := getSupport(filter) / step
 	taps := int(math.Ceil(support)) * 2
 	if !cfg.Vertical && taps == 6 && hasAsm() && !cfg.DisableAsm {
 		taps = 8
@@ -52,33 +77,80 @@ func makeDoubleKernel(cfg *ResizerConfig, filter Filter, field, idx uint) ([]int
 	offsets := make([]int16, cfg.Output)
 	sums := make([]float64, cfg.Output)
 	weights := make([]float64, cfg.Output*taps)
-	xmid := float64(cfg.Input-cfg.Output) / float64(cfg.Output*2)
 	xstep := 1 / scale
 	// interlaced resize see only one field but still use full res pixel positions
+	// split resizes write one field from every progressive input pixel
 	ftaps := taps << field
-	size := (cfg.Output + int(field*(1-idx))) >> field
+	fields := field | bin(cfg.split)
+	size := (cfg.Output + int(fields*(1-idx))) >> fields
 	step /= float64(1 + field)
-	xmid += xstep * float64(field*idx)
+	xmid += xstep * float64(fields*idx)
 	for i := 0; i < size; i++ {
 		left := int(math.Ceil(xmid)) - ftaps>>1
 		x := clip(left, 0, max(0, cfg.Input-ftaps))
 		offsets[i] = int16(x)
+		near, dmin := 0, math.Inf(1)
 		for j := 0; j < ftaps; j++ {
 			src := left + j
 			if field != 0 && idx^uint(src&1) != 0 {
 				continue
 			}
-			weight := filter.Get(math.Abs(xmid-float64(src)) * step)
+			dist := math.Abs(xmid - float64(src))
+			weight := filter.Get(dist * step)
 			src = clip(src, x, cfg.Input-1) - x
 			src >>= field
+			if dist < dmin {
+				near, dmin = src, dist
+			}
 			weights[i*taps+src] += weight
 			sums[i] += weight
 		}
-		xmid += xstep * float64(1+field)
+		if !fitsCoeffs(weights[i*taps:i*taps+taps], sums[i]) {
+			// narrow filters may miss every input pixel on upscales, or
+			// only see their negative lobes
+			for j := range weights[i*taps : i*taps+taps] {
+				weights[i*taps+j] = 0
+			}
+			weights[i*taps+near] = 1
+			sums[i] = 1
+		}
+		if point {
+			sums[i] = keepMaxWeight(weights[i*taps : i*taps+taps])
+		}
+		xmid += xstep * float64(1+fields)
 	}
 	return offsets, sums, weights, taps, size
 }
 
+// fitsCoeffs returns whether weights normalized by sum fit int16 coefficients
+func fitsCoeffs(weights []float64, sum float64) bool {
+	if !(sum > 0) {
+		return false
+	}
+	for _, w := range weights {
+		if math.Abs(w)*(1<<Bits) >= sum*(1<<15) {
+			return false
+		}
+	}
+	return true
+}
+
+// keepMaxWeight zeroes every weight but the first biggest one and returns it
+func keepMaxWeight(weights []float64) float64 {
+	best := 0
+	for i, w := range weights {
+		if w > weights[best] {
+			best = i
+		}
+	}
+	w := weights[best]
+	for i := range weights {
+		weights[i] = 0
+	}
+	weights[best] = w
+	return w
+}
+
 type weight struct {
 	weight float64
 	offset int
@@ -124,9 +196,9 @@ func makeIntegerKernel(taps, size int, cof, sums []float64, pos []int16, field,
 }
 
 func makeKernel(cfg *ResizerConfig, filter Filter, idx uint) kernel {
-	field := bin(cfg.Interlaced)
+	field := bin(cfg.Interlaced && !cfg.split)
 	pos, sums, cof, taps, size := makeDoubleKernel(cfg, filter, field, idx)
-	coeffs, offsets := makeIntegerKernel(taps, size, cof, sums, pos, field, idx)
+	coeffs, offsets := makeIntegerKernel(taps, size, cof, sums, pos, field, idx&field)
 	//coeffs, offsets = reduceKernel(coeffs, offsets, taps, size)
 	if cfg.Vertical {
 		for i := len(offsets) - 1; i > 0; i-- {
@@ -136,8 +208,12 @@ func makeKernel(cfg *ResizerConfig, filter Filter, idx uint) kernel {
 	} else if cfg.Pack > 1 {
 		coeffs, offsets, taps = unpack(coeffs, offsets, taps, cfg.Pack)
 	}
+	raw := coeffs
 	coeffs, cofscale := prepareCoeffs(cfg, coeffs, size, taps)
-	return kernel{coeffs, offsets, taps, cofscale}
+	if !cfg.Vertical && hasAsm() && !cfg.DisableAsm {
+		offsets = padOffsets(offsets)
+	}
+	return kernel{coeffs, offsets, taps, cofscale, raw}
 }
 
 func prepareCoeffs(cfg *ResizerConfig, cof []int16, size, taps int) ([]int16, int) {
@@ -147,6 +223,10 @@ func prepareCoeffs(cfg *ResizerConfig, cof []int16, size, taps int) ([]int16, in
 	if cfg.Vertical {
 		return prepareVerticalCoeffs(cof, size, taps)
 	}
+	if cfg.AntiRinging > 0 {
+		// ring scalers only support interleaved taps
+		return interleaveCoeffs(cof, size*cfg.Pack, taps), 1
+	}
 	return prepareHorizontalCoeffs(cof, size*cfg.Pack, taps), 1
 }
 
@@ -168,15 +248,27 @@ func prepareVerticalCoeffs(cof []int16, size, taps int) ([]int16, int) {
 	return dst, xwidth >> 1
 }
 
+// prepareHorizontalCoeffs returns coefficients for horizontal simd scalers
+// The last simd loop of a row runs on a full block, so the returned slice
+// has room for zeroed coefficients up to the end of that block
 func prepareHorizontalCoeffs(cof []int16, size, taps int) []int16 {
 	if taps == 2 || taps == 4 || taps == 8 {
-		return cof
+		xwidth := 16
+		loop := (size + xwidth - 1) / xwidth
+		dst := make([]int16, len(cof), loop*xwidth*taps)
+		copy(dst, cof)
+		return dst
 	}
+	return interleaveCoeffs(cof, size, taps)
+}
+
+// interleaveCoeffs returns coefficients interleaved by pairs of taps over
+// simd blocks, up to the end of the last block
+func interleaveCoeffs(cof []int16, size, taps int) []int16 {
 	xwidth := 16
-	dst := make([]int16, len(cof))
-	loop := size / xwidth
-	left := (size - loop*xwidth) * taps
-	si := 0
+	loop := (size + xwidth - 1) / xwidth
+	dst := make([]int16, len(cof), loop*xwidth*taps)
+	full := dst[:cap(dst)]
 	di := 0
 	// instead of having all taps contiguous for one destination pixel,
 	// we store 2 taps per pixel and fill one simd-sized buffer with it, then
@@ -184,17 +276,24 @@ func prepareHorizontalCoeffs(cof []int16, size, taps int) []int16 {
 	// this way we don't care about the simd register size, we will always be
 	// able to process N pixels at once
 	for i := 0; i < loop; i++ {
-		for j := 0; j*2 < taps; j++ {
-			for k := 0; k < xwidth; k++ {
-				dst[di+k*2+0] = cof[si+k*taps+0]
-				dst[di+k*2+1] = cof[si+k*taps+1]
+		n := min(xwidth, size-i*xwidth)
+		for j := 0; j < taps; j += 2 {
+			for k, si := 0, i*xwidth*taps+j; k < n; k, si = k+1, si+taps {
+				full[di+k*2+0] = cof[si+0]
+				full[di+k*2+1] = cof[si+1]
 			}
 			di += xwidth * 2
-			si += 2
 		}
-		si = di
 	}
-	copy(dst[di:di+left], cof[si:si+left])
+	return dst
+}
+
+// padOffsets returns offsets with room for zeroed offsets up to the end of
+// the last simd block, which only reads the first input pixels
+func padOffsets(offsets []int16) []int16 {
+	xwidth := 16
+	dst := make([]int16, len(offsets), (len(offsets)+xwidth-1)&^(xwidth-1))
+	copy(dst, offsets)
 	return dst
 }
 
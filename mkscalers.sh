@@ -5,3 +5,4 @@ go fmt fixedscalers.go
 go install -v .../rez/rezgen
 rezgen -gen horizontal > hscalers_amd64.s && echo hscalers_amd64.s
 rezgen -gen vertical   > vscalers_amd64.s && echo vscalers_amd64.s
+rezgen -gen fast       > fscalers_amd64.s && echo fscalers_amd64.s
@@ -0,0 +1,96 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+// multiContext is a resizer chaining 2:1 box reductions with a final
+// filter pass
+type multiContext struct {
+	cfg     ResizerConfig
+	sizes   []int // sizes after each stage
+	stages  []Resizer
+	scratch freeList // ping-pong stage buffers
+}
+
+func needMultiResize(cfg *ResizerConfig) bool {
+	// interlaced fields would need even sizes on every stage
+	if cfg.Vertical && cfg.Interlaced {
+		return false
+	}
+	return cfg.MaxFilterRatio > 1 &&
+		float64(cfg.Input) > float64(cfg.Output)*cfg.MaxFilterRatio &&
+		(cfg.Input+1)>>1 >= cfg.Output
+}
+
+func newMultiResize(cfg *ResizerConfig, filter Filter) Resizer {
+	m := &multiContext{
+		cfg: *cfg,
+	}
+	if m.cfg.Pack < 1 {
+		m.cfg.Pack = 1
+	}
+	m.scratch.alloc = func() interface{} {
+		return new([2][]byte)
+	}
+	next := m.cfg
+	for needMultiResize(&next) {
+		box := next
+		box.Output = (box.Input + 1) >> 1
+		box.MaxFilterRatio = 0
+		box.AntiRinging = 0
+		m.sizes = append(m.sizes, box.Output)
+		m.stages = append(m.stages, NewResize(&box, NewBoxFilter()))
+		next.Input = box.Output
+	}
+	next.MaxFilterRatio = 0
+	m.sizes = append(m.sizes, next.Output)
+	m.stages = append(m.stages, NewResize(&next, filter))
+	return m
+}
+
+// getPlane returns a stage output pitch & buffer size in bytes
+func (m *multiContext) getPlane(stage, width, height int) (int, int) {
+	pk := m.cfg.Pack
+	size := m.sizes[stage]
+	if m.cfg.Vertical {
+		pitch := align(width*pk, 16)
+		return pitch, pitch*(size-1) + width*pk
+	}
+	pitch := align(size*pk, 16)
+	return pitch, pitch*(height-1) + size*pk
+}
+
+func (m *multiContext) Resize(dst, src []byte, width, height, dp, sp int) {
+	if pool := m.cfg.Pool; pool.enter() == nil {
+		defer pool.leave()
+	}
+	m.resize(nil, dst, src, width, height, dp, sp)
+}
+
+func (m *multiContext) resize(done <-chan struct{}, dst, src []byte, width, height, dp, sp int) {
+	buffers := m.scratch.get().(*[2][]byte)
+	last := len(m.stages) - 1
+	for i, stage := range m.stages {
+		if canceled(done) {
+			break
+		}
+		next, np := dst, dp
+		if i != last {
+			pitch, size := m.getPlane(i, width, height)
+			buf := &buffers[i&1]
+			if len(*buf) < size {
+				*buf = make([]byte, size)
+			}
+			next, np = (*buf)[:size], pitch
+		}
+		resizeWith(done, stage, next, src, width, height, np, sp)
+		src, sp = next, np
+		if m.cfg.Vertical {
+			height = m.sizes[i]
+		} else {
+			width = m.sizes[i]
+		}
+	}
+	m.scratch.put(buffers)
+}
@@ -0,0 +1,268 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+import (
+	gocontext "context"
+	"fmt"
+	"image"
+	"runtime"
+	"sync"
+)
+
+// MultiConverter is an interface that converts one input image into several
+// outputs at once
+type MultiConverter interface {
+	// Converts input into every output, outputs must be in the same order
+	// as the descriptors used when creating the MultiConverter
+	Convert(outputs []image.Image, input image.Image) error
+	// Converts like Convert, but stops as soon as possible once ctx is done
+	// Returns ctx.Err() if ctx is done, outputs content is undefined then
+	ConvertContext(ctx gocontext.Context, outputs []image.Image, input image.Image) error
+}
+
+type multiConverter struct {
+	input   Descriptor
+	threads int
+	pool    *Pool
+	outputs []*converterContext
+	deint   []bool // whether output uses deinterlaced planes
+	dint    [maxPlanes]*deinterlacer
+	dbuf    [maxPlanes]*Plane // deinterlaced plane templates
+	scratch freeList          // intermediate & deinterlaced planes
+	verify  []*verifier       // optional Go only converters per output, see Verify
+}
+
+// getThreads splits threads across outputs by pixel count
+func getThreads(threads int, outputs []Descriptor) []int {
+	total := 0
+	for _, d := range outputs {
+		total += d.Width * d.Height
+	}
+	split := make([]int, len(outputs))
+	for i, d := range outputs {
+		split[i] = max(1, threads*d.Width*d.Height/max(total, 1))
+	}
+	return split
+}
+
+// canShareVertical returns whether plane intermediate results from a can be
+// used by b
+func canShareVertical(a, b *converterContext, plane int) bool {
+	return a.buffer[plane] != nil && b.buffer[plane] != nil &&
+		a.plan[plane].Order == VerticalFirst &&
+		b.plan[plane].Order == VerticalFirst &&
+		a.Input == b.Input &&
+		a.Output.fieldHeight(plane) == b.Output.fieldHeight(plane) &&
+		a.Output.interlacedFrame() == b.Output.interlacedFrame()
+}
+
+// NewMultiConverter returns a MultiConverter interface
+// cfg = shared converter configuration, cfg.Output is ignored
+// outputs = every output description
+// filter = filter used for resizing planes without cfg.Filters
+// cfg.Verify checks every output against Go scalers
+// Input reads, deinterlacing and vertical passes are shared between outputs
+// when possible, and cfg.Threads is split across all outputs
+// Returns an error if any conversion is invalid or not implemented
+func NewMultiConverter(cfg *ConverterConfig, outputs []Descriptor, filter Filter) (MultiConverter, error) {
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("missing outputs")
+	}
+	err := checkDeinterlaceMode(cfg.Deinterlace)
+	if err != nil {
+		return nil, err
+	}
+	m := &multiConverter{
+		input:   cfg.Input,
+		threads: cfg.Threads,
+		pool:    cfg.Pool,
+		deint:   make([]bool, len(outputs)),
+	}
+	if m.threads == 0 {
+		m.threads = runtime.GOMAXPROCS(0)
+	}
+	threads := getThreads(m.threads, outputs)
+	for k, output := range outputs {
+		c := *cfg
+		c.Output = output
+		c.Threads = threads[k]
+		if cfg.Input.interlacedFrame() && !output.Interlaced {
+			// deinterlaced once for all outputs
+			m.deint[k] = true
+			c.Input.Interlaced = false
+		}
+		ctx, err := newConverterContext(&c, filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output %v: %v", k, err)
+		}
+		m.outputs = append(m.outputs, ctx)
+		if cfg.Verify && hasAsm() && !cfg.DisableAsm {
+			c.Input = cfg.Input
+			v, err := newVerifier(&c, filter)
+			if err != nil {
+				return nil, fmt.Errorf("invalid output %v: %v", k, err)
+			}
+			m.verify = append(m.verify, v)
+		}
+	}
+	shared := make([][maxPlanes]*converterContext, len(outputs))
+	for k, ctx := range m.outputs {
+		for i := 0; i < ctx.Output.Planes; i++ {
+			for _, prev := range m.outputs[:k] {
+				if canShareVertical(prev, ctx, i) {
+					shared[k][i] = prev
+					ctx.hrez[i] = nil
+					ctx.buffer[i] = nil
+					break
+				}
+			}
+		}
+	}
+	for k := range m.outputs {
+		if !m.deint[k] || m.dint[0] != nil {
+			continue
+		}
+		for i := 0; i < cfg.Input.Planes; i++ {
+			win := cfg.Input.GetWidth(i)
+			hin := cfg.Input.GetHeight(i)
+			_, vfilter := cfg.getFilters(i, filter)
+			m.dint[i] = newDeinterlacer(cfg.Deinterlace, cfg.Input.Order, win, hin,
+				cfg.Input.Pack, m.threads, cfg.DisableAsm, cfg.Pool, vfilter)
+			m.dbuf[i] = &Plane{
+				Width:  win,
+				Height: hin,
+				Pitch:  align(win*cfg.Input.Pack, 16),
+				Pack:   cfg.Input.Pack,
+			}
+		}
+	}
+	templates := []*Plane{}
+	for k, ctx := range m.outputs {
+		for i, prev := range shared[k] {
+			if prev != nil {
+				ctx.buffer[i] = prev.buffer[i]
+			}
+		}
+		templates = append(templates, ctx.buffer[:]...)
+	}
+	templates = append(templates, m.dbuf[:]...)
+	m.scratch.alloc = func() interface{} {
+		return newScratch(templates)
+	}
+	m.scratch.put(m.scratch.get())
+	return m, nil
+}
+
+func (m *multiConverter) Convert(outputs []image.Image, input image.Image) error {
+	return m.convert(nil, outputs, input)
+}
+
+func (m *multiConverter) ConvertContext(c gocontext.Context, outputs []image.Image, input image.Image) error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	if err := m.convert(c.Done(), outputs, input); err != nil {
+		return err
+	}
+	return c.Err()
+}
+
+// convert converts input into outputs, stopping early once done is closed
+func (m *multiConverter) convert(done <-chan struct{}, outputs []image.Image, input image.Image) error {
+	if len(outputs) != len(m.outputs) {
+		return fmt.Errorf("unable to convert %v outputs with a %v outputs converter",
+			len(outputs), len(m.outputs))
+	}
+	err := m.pool.enter()
+	if err != nil {
+		return err
+	}
+	defer m.pool.leave()
+	id, src, err := inspect(input, m.input.Interlaced)
+	if err != nil {
+		return err
+	}
+	id.Order, id.Field = m.input.Order, m.input.Field
+	selectField(src, id)
+	dsts := make([][]Plane, len(outputs))
+	for k, output := range outputs {
+		ctx := m.outputs[k]
+		od, dst, err := inspect(output, ctx.Output.Interlaced)
+		if err != nil {
+			return err
+		}
+		od.Order, od.Field = ctx.Output.Order, ctx.Output.Field
+		err = checkConversion(od, id)
+		if err != nil {
+			return err
+		}
+		selectField(dst, od)
+		dsts[k] = dst
+	}
+	buf := m.scratch.get().(*scratch)
+	group := sync.WaitGroup{}
+	if m.dint[0] != nil {
+		for i := 0; i < m.input.Planes; i++ {
+			dint, dbuf, src := m.dint[i], m.getDeinterlaced(buf, i), &src[i]
+			dispatch(m.pool, &group, m.threads, taskFunc(func() {
+				dint.Deinterlace(dbuf, src, done)
+			}))
+		}
+		group.Wait()
+	}
+	// first passes must complete before shared intermediate planes are
+	// read by second passes
+	for k, ctx := range m.outputs {
+		for i := 0; i < m.input.Planes; i++ {
+			first, _ := ctx.getPasses(i)
+			d := buf.planes[k*maxPlanes+i]
+			if first == nil || d == nil {
+				continue
+			}
+			s := m.getSource(buf, k, i, src)
+			dispatch(m.pool, &group, m.threads, taskFunc(func() {
+				resizeWith(done, first, d.Data, s.Data, s.Width, s.Height, d.Pitch, s.Pitch)
+			}))
+		}
+	}
+	group.Wait()
+	for k, ctx := range m.outputs {
+		for i := 0; i < m.input.Planes; i++ {
+			last, sharp, strip := ctx.getLastPass(i), ctx.sharp[i], ctx.strip[i]
+			s, d := m.getSource(buf, k, i, src), &dsts[k][i]
+			if p := buf.planes[k*maxPlanes+i]; p != nil {
+				s = p
+			}
+			dispatch(m.pool, &group, m.threads, taskFunc(func() {
+				resizeLast(done, d, s, last, strip, sharp)
+			}))
+		}
+	}
+	group.Wait()
+	m.scratch.put(buf)
+	for k, v := range m.verify {
+		if canceled(done) {
+			break
+		}
+		if err := v.check(done, dsts[k], src); err != nil {
+			return fmt.Errorf("output %v: %v", k, err)
+		}
+	}
+	return nil
+}
+
+// getDeinterlaced returns the deinterlaced plane from scratch planes
+func (m *multiConverter) getDeinterlaced(buf *scratch, plane int) *Plane {
+	return buf.planes[len(m.outputs)*maxPlanes+plane]
+}
+
+// getSource returns the input plane used by an output
+func (m *multiConverter) getSource(buf *scratch, output, plane int, src []Plane) *Plane {
+	if m.deint[output] {
+		return m.getDeinterlaced(buf, plane)
+	}
+	return &src[plane]
+}
@@ -0,0 +1,119 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+import (
+	"fmt"
+)
+
+// PassOrder is the order of resize passes on a plane
+type PassOrder int
+
+const (
+	// VerticalFirst resizes rows before columns
+	VerticalFirst PassOrder = iota
+	// HorizontalFirst resizes columns before rows
+	HorizontalFirst
+)
+
+func (o PassOrder) String() string {
+	switch o {
+	case VerticalFirst:
+		return "vertical-first"
+	case HorizontalFirst:
+		return "horizontal-first"
+	}
+	return fmt.Sprintf("PassOrder(%d)", int(o))
+}
+
+// PlanePlan describes how a converter resizes one plane
+type PlanePlan struct {
+	Order  PassOrder // pass order
+	Cost   int       // estimated multiply-adds per plane
+	Width  int       // intermediate plane width, 0 without intermediate plane
+	Height int       // intermediate plane height, 0 without intermediate plane
+	Strips bool      // whether passes are strip-mined, see StripRows
+}
+
+// Planner is implemented by converters able to describe their resize plan
+// Converters returned by NewConverter implement it
+type Planner interface {
+	// Returns how every plane is resized
+	Plan() []PlanePlan
+}
+
+// coster is implemented by resizers able to estimate their cost
+type coster interface {
+	// cost returns the number of multiply-adds needed to resize a plane
+	// other = plane size in pixels along the other dimension
+	cost(other int) int
+}
+
+func (c *context) cost(other int) int {
+	return c.taps * c.cfg.Output * c.cfg.Pack * other
+}
+
+func (m *multiContext) cost(other int) int {
+	sum := 0
+	for _, stage := range m.stages {
+		sum += getCost(stage, other)
+	}
+	return sum
+}
+
+func getCost(r Resizer, other int) int {
+	if c, ok := r.(coster); ok {
+		return c.cost(other)
+	}
+	return 0
+}
+
+// getPlan returns the cheapest pass order for a plane
+// hrez, wrez = vertical & horizontal resizers, if any
+func getPlan(hrez, wrez Resizer, win, hin, wout, hout int) PlanePlan {
+	vfirst := getCost(hrez, win) + getCost(wrez, hout)
+	hfirst := getCost(wrez, hin) + getCost(hrez, wout)
+	plan := PlanePlan{
+		Order: VerticalFirst,
+		Cost:  vfirst,
+	}
+	if hfirst < vfirst {
+		plan.Order = HorizontalFirst
+		plan.Cost = hfirst
+	}
+	if hrez == nil || wrez == nil {
+		return plan
+	}
+	plan.Width, plan.Height = win, hout
+	if plan.Order == HorizontalFirst {
+		plan.Width, plan.Height = wout, hin
+	}
+	return plan
+}
+
+// getPasses returns plane resizers in execution order
+func (ctx *converterContext) getPasses(plane int) (Resizer, Resizer) {
+	if ctx.plan[plane].Order == HorizontalFirst {
+		return ctx.wrez[plane], ctx.hrez[plane]
+	}
+	return ctx.hrez[plane], ctx.wrez[plane]
+}
+
+// getLastPass returns the resizer writing output planes, nil for copies &
+// strips
+func (ctx *converterContext) getLastPass(plane int) Resizer {
+	first, second := ctx.getPasses(plane)
+	if ctx.strip[plane] != nil {
+		return nil
+	}
+	if second != nil {
+		return second
+	}
+	return first
+}
+
+func (ctx *converterContext) Plan() []PlanePlan {
+	return append([]PlanePlan{}, ctx.plan[:ctx.Output.Planes]...)
+}
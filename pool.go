@@ -0,0 +1,152 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Pool is a set of persistent workers running resize jobs
+// It can be shared between converters to bound the number of concurrent
+// jobs for the whole process
+// Every conversion holds one slot of the pool while it runs, and waits for
+// a free slot before starting, so that workers plus converting goroutines
+// never exceed the pool size
+// Jobs run on the converting goroutine when no other slot is free
+// Converters without a Pool share a default pool of GOMAXPROCS workers,
+// and never wait for free slots
+type Pool struct {
+	slots chan struct{} // one token per running goroutine
+	jobs  chan job
+	quit  chan struct{}
+	once  sync.Once
+}
+
+// task is a parallel job
+// Tasks are preallocated by their owners, so that dispatching them never
+// allocates
+type task interface {
+	run()
+}
+
+// taskFunc is a task running a closure, for jobs allowed to allocate
+type taskFunc func()
+
+func (f taskFunc) run() {
+	f()
+}
+
+// job is a task waited by group
+type job struct {
+	task  task
+	group *sync.WaitGroup
+}
+
+var (
+	errPoolClosed = fmt.Errorf("pool is closed")
+	defaultPool   *Pool
+	defaultOnce   sync.Once
+)
+
+// NewPool returns a new Pool
+// workers = number of concurrent jobs, [default=GOMAXPROCS]
+func NewPool(workers int) *Pool {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p := &Pool{
+		slots: make(chan struct{}, workers),
+		jobs:  make(chan job),
+		quit:  make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// getPool returns p, or the default pool if p is nil
+func getPool(p *Pool) *Pool {
+	if p != nil {
+		return p
+	}
+	defaultOnce.Do(func() {
+		defaultPool = NewPool(0)
+	})
+	return defaultPool
+}
+
+func (p *Pool) work() {
+	for j := range p.jobs {
+		j.task.run()
+		<-p.slots
+		j.group.Done()
+	}
+}
+
+// Close waits for running conversions and stops every worker
+// Conversions using p fail once Close is called
+func (p *Pool) Close() {
+	p.once.Do(func() {
+		close(p.quit)
+		for i := 0; i < cap(p.slots); i++ {
+			p.slots <- struct{}{}
+		}
+		close(p.jobs)
+	})
+}
+
+// enter waits for a free slot for the calling goroutine
+// A nil pool never waits
+// Returns an error if p is closed
+func (p *Pool) enter() error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case <-p.quit:
+		return errPoolClosed
+	case p.slots <- struct{}{}:
+	}
+	select {
+	case <-p.quit:
+		<-p.slots
+		return errPoolClosed
+	default:
+		return nil
+	}
+}
+
+// leave releases the slot taken by enter
+func (p *Pool) leave() {
+	if p != nil {
+		<-p.slots
+	}
+}
+
+// run runs j on an idle worker if a slot is free, else on the calling
+// goroutine, which must hold a slot unless p is the default pool
+func (p *Pool) run(j job) {
+	select {
+	case p.slots <- struct{}{}:
+		p.jobs <- j
+	default:
+		j.task.run()
+		j.group.Done()
+	}
+}
+
+// dispatch runs t in parallel, group waits for it
+// Serial tasks run on the calling goroutine
+func dispatch(pool *Pool, group *sync.WaitGroup, threads int, t task) {
+	if threads == 1 {
+		t.run()
+		return
+	}
+	group.Add(1)
+	getPool(pool).run(job{t, group})
+}
@@ -0,0 +1,174 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+import (
+	"fmt"
+	"image"
+)
+
+// Pyramid converts one image into a chain of mipmap levels
+// Every level is resized from the previous one
+type Pyramid struct {
+	converters []Converter
+}
+
+// newImageLike allocates an image with the same format as img & aligned
+// strides
+func newImageLike(img image.Image, width, height int) (image.Image, error) {
+	rect := image.Rect(0, 0, width, height)
+	switch t := img.(type) {
+	case *image.YCbCr:
+		return NewYCbCr(rect, t.SubsampleRatio), nil
+	case *image.RGBA:
+		return NewRGBA(rect), nil
+	case *image.NRGBA:
+		return NewNRGBA(rect), nil
+	case *image.Gray:
+		return NewGray(rect), nil
+	}
+	return nil, fmt.Errorf("unknown image format")
+}
+
+// checkMinSize returns whether every plane is big enough to be resized
+func checkMinSize(d *Descriptor) bool {
+	for i := 0; i < d.Planes; i++ {
+		if d.GetWidth(i) < 2 || d.GetHeight(i) < 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// NewPyramidImages allocates mipmap levels for src, halving dimensions and
+// rounding them up at each level
+// count = maximum number of levels, 0 for as many levels as possible
+// Levels stop after the 1x1 level
+func NewPyramidImages(src image.Image, count int) ([]image.Image, error) {
+	d, _, err := inspect(src, false)
+	if err != nil {
+		return nil, err
+	}
+	levels := []image.Image{}
+	for (d.Width > 1 || d.Height > 1) && (count == 0 || len(levels) < count) {
+		d.Width = (d.Width + 1) >> 1
+		d.Height = (d.Height + 1) >> 1
+		img, err := newImageLike(src, d.Width, d.Height)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, img)
+	}
+	return levels, nil
+}
+
+// averager halves tiny images which converters cannot resize, with a box
+// average on every plane
+type averager struct{}
+
+// newAverager returns an averager from src to dst, or an error unless dst is
+// src halved & rounded up
+func newAverager(dst, src *Descriptor) (Converter, error) {
+	if dst.Width != (src.Width+1)>>1 || dst.Height != (src.Height+1)>>1 {
+		return nil, fmt.Errorf("unable to resize %vx%v into %vx%v",
+			src.Width, src.Height, dst.Width, dst.Height)
+	}
+	return averager{}, nil
+}
+
+func (averager) Convert(output, input image.Image) error {
+	od, dst, err := inspect(output, false)
+	if err != nil {
+		return err
+	}
+	id, src, err := inspect(input, false)
+	if err != nil {
+		return err
+	}
+	err = checkConversion(od, id)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < od.Planes; i++ {
+		averagePlane(&dst[i], &src[i])
+	}
+	return nil
+}
+
+// averagePlane sets every dst pixel to the average of up to 2x2 src pixels
+func averagePlane(dst, src *Plane) {
+	for y := 0; y < dst.Height; y++ {
+		d := dst.Data[dst.Pitch*y:]
+		for x := 0; x < dst.Width; x++ {
+			for c := 0; c < dst.Pack; c++ {
+				sum, n := 0, 0
+				for j := y * 2; j < min(y*2+2, src.Height); j++ {
+					for i := x * 2; i < min(x*2+2, src.Width); i++ {
+						sum += int(src.Data[src.Pitch*j+src.Pack*i+c])
+						n++
+					}
+				}
+				d[dst.Pack*x+c] = byte((sum + n/2) / n)
+			}
+		}
+	}
+}
+
+// NewPyramid returns a Pyramid converting src into levels
+// levels = mipmap level images, from biggest to smallest
+// src = source image
+// filter = filter used for resizing every level
+// Returns an error if any level conversion is invalid
+func NewPyramid(levels []image.Image, src image.Image, filter Filter) (*Pyramid, error) {
+	p := &Pyramid{}
+	prev := src
+	for i, level := range levels {
+		cfg, err := PrepareConversion(level, prev)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level %v: %v", i, err)
+		}
+		var converter Converter
+		if checkMinSize(&cfg.Input) && checkMinSize(&cfg.Output) {
+			converter, err = NewConverter(cfg, filter)
+		} else {
+			converter, err = newAverager(&cfg.Output, &cfg.Input)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid level %v: %v", i, err)
+		}
+		p.converters = append(p.converters, converter)
+		prev = level
+	}
+	return p, nil
+}
+
+// Convert resizes src into every level
+// Returns an error if levels do not match the pyramid
+func (p *Pyramid) Convert(levels []image.Image, src image.Image) error {
+	if len(levels) != len(p.converters) {
+		return fmt.Errorf("unable to convert %v levels with a %v levels pyramid",
+			len(levels), len(p.converters))
+	}
+	prev := src
+	for i, level := range levels {
+		err := p.converters[i].Convert(level, prev)
+		if err != nil {
+			return err
+		}
+		prev = level
+	}
+	return nil
+}
+
+// ConvertPyramid resizes src into every mipmap level using the input filter
+// Note that if you plan to build pyramids over and over, it is faster to
+// use a Pyramid
+func ConvertPyramid(levels []image.Image, src image.Image, filter Filter) error {
+	p, err := NewPyramid(levels, src, filter)
+	if err != nil {
+		return err
+	}
+	return p.Convert(levels, src)
+}
@@ -18,6 +18,22 @@ type ResizerConfig struct {
 	Pack       int  // pixels per pack [default=1]
 	Threads    int  // number of threads, [default=0]
 	DisableAsm bool // disable asm optimisations
+	// AntiRinging limits output pixels to the range of their significant
+	// input pixels, from 0 (disabled) to 1 (full clamp)
+	AntiRinging float64
+	// MaxFilterRatio is the largest downscale ratio done with a single
+	// filter pass, bigger ratios are first reduced with fast 2:1 box passes
+	// Lower values are faster, higher values are sharper [default=0=disabled]
+	MaxFilterRatio float64
+	// Pool runs parallel jobs when set, instead of new goroutines
+	// Resize waits for a free pool slot before resizing
+	Pool *Pool
+	// shift, when non zero, centers output pixel i on input pixel i+shift
+	// without scaling, deinterlacers use it to rebuild missing field lines
+	shift float64
+	// split resizes progressive input into both fields of interlaced
+	// output, filtered at the field rate, only used with Interlaced
+	split bool
 }
 
 // Resizer is a interface that implements resizes
@@ -32,10 +48,20 @@ type Resizer interface {
 type scaler func(dst, src []byte, cof, off []int16,
 	taps, width, height, dstPitch, srcPitch int)
 
+// bandScaler scales a tile at kernel position pos without kernel tables,
+// pos = first output row of vertical tiles or first output byte of
+// horizontal tiles
+type bandScaler func(dst, src []byte, pos, width, height, dstPitch, srcPitch int)
+
 type context struct {
 	cfg     ResizerConfig
 	kernels []kernel
 	scaler  scaler
+	starts  []int      // first input row of every output row, vertical only
+	taps    int        // filter taps without simd padding, for cost estimates
+	band    bandScaler // replaces scaler when set
+	columns bool       // whether scaler accepts column tiles
+	jobs    freeList   // tile jobs
 }
 
 func getHorizontalScalerGo(taps int) scaler {
@@ -78,6 +104,9 @@ func getVerticalScalerGo(taps int) scaler {
 // cfg = resize configuration
 // filter = filter used for computing weights
 func NewResize(cfg *ResizerConfig, filter Filter) Resizer {
+	if needMultiResize(cfg) {
+		return newMultiResize(cfg, filter)
+	}
 	ctx := context{
 		cfg: *cfg,
 	}
@@ -86,99 +115,214 @@ func NewResize(cfg *ResizerConfig, filter Filter) Resizer {
 		ctx.cfg.Pack = 1
 	}
 	ctx.kernels = []kernel{makeKernel(&ctx.cfg, filter, 0)}
+	ctx.taps = getTaps(&ctx.cfg, filter)
 	ctx.scaler = getHorizontalScaler(ctx.kernels[0].size, !cfg.DisableAsm)
+	ctx.columns = true
 	if cfg.Vertical {
 		ctx.scaler = getVerticalScaler(ctx.kernels[0].size, !cfg.DisableAsm)
 		if cfg.Interlaced {
 			ctx.kernels = append(ctx.kernels, makeKernel(&ctx.cfg, filter, 1))
+		} else {
+			ctx.starts = getStarts(ctx.kernels[0].offsets)
 		}
 	}
+	if fast := makeFastKernel(&ctx.cfg, &ctx.kernels[0], !cfg.DisableAsm); fast != nil {
+		ctx.band = fast.scaleColumns
+		if cfg.Vertical {
+			ctx.band = fast.scaleVertical
+		}
+	}
+	if cfg.AntiRinging > 0 {
+		ctx.scaler = getRingScaler(cfg.Vertical, cfg.AntiRinging, !cfg.DisableAsm)
+		ctx.band = nil
+		ctx.columns = true
+	}
+	threads := ctx.cfg.Threads
+	ctx.jobs.alloc = func() interface{} {
+		return newTileJobs(threads)
+	}
 	return &ctx
 }
 
-func dispatch(group *sync.WaitGroup, threads int, job func()) {
-	if threads == 1 {
-		job()
-	} else {
-		group.Add(1)
-		go func() {
-			job()
-			group.Done()
-		}()
+// tileJob scales one tile of a pass
+type tileJob struct {
+	done     <-chan struct{}
+	scaler   scaler
+	band     bandScaler
+	pos      int // band position
+	vertical bool
+	dst, src []byte
+	cof      []int16
+	cofscale int
+	off      []int16
+	taps     int
+	width    int
+	height   int
+	dp, sp   int
+}
+
+func (j *tileJob) run() {
+	scaleTile(j.done, j.scaler, j.band, j.pos, j.vertical, j.dst, j.src, j.cof, j.cofscale, j.off,
+		j.taps, j.width, j.height, j.dp, j.sp)
+}
+
+// tileJobs holds preallocated jobs for every tile of a resize
+type tileJobs struct {
+	group sync.WaitGroup
+	jobs  []tileJob
+}
+
+// newTileJobs returns room for every tile of both fields
+func newTileJobs(threads int) *tileJobs {
+	return &tileJobs{
+		jobs: make([]tileJob, 0, 2*max(threads, 1)),
 	}
 }
 
-func scaleSlice(group *sync.WaitGroup, threads int, scaler scaler,
-	dst, src []byte, cof, off []int16, taps, width, height, dp, sp int) {
-	dispatch(group, threads, func() {
+// reset waits for every tile and releases their buffers
+func (t *tileJobs) reset() {
+	t.group.Wait()
+	for i := range t.jobs {
+		t.jobs[i] = tileJob{}
+	}
+	t.jobs = t.jobs[:0]
+}
+
+// scaleTile scales one tile on the calling goroutine
+func scaleTile(done <-chan struct{}, scaler scaler, band bandScaler, pos int, vertical bool,
+	dst, src []byte, cof []int16, cofscale int, off []int16,
+	taps, width, height, dp, sp int) {
+	if done != nil {
+		scaleRows(done, scaler, band, pos, vertical, dst, src, cof, cofscale, off,
+			taps, width, height, dp, sp)
+	} else if band != nil {
+		band(dst, src, pos, width, height, dp, sp)
+	} else {
 		scaler(dst, src, cof, off, taps, width, height, dp, sp)
-	})
+	}
 }
 
-func scaleSlices(group *sync.WaitGroup, scaler scaler,
-	vertical bool, threads, taps, width, height, dp, sp int,
+const (
+	// columnAlign is the column tile alignment in bytes, so that simd
+	// scalers see the same blocks as with full rows
+	columnAlign = 16
+)
+
+// getTiles returns the number of row bands & column tiles of a pass
+// columns = whether the scaler accepts column tiles
+func getTiles(threads, width, height int, columns bool) (int, int) {
+	rows := max(1, min(threads, height))
+	cols := 1
+	if columns {
+		cols = max(1, min(threads/rows, width/columnAlign))
+	}
+	return rows, cols
+}
+
+// scaleTiles splits a pass in row bands, and in column tiles when there
+// are more threads than rows, and dispatches every tile with t jobs
+func scaleTiles(t *tileJobs, pool *Pool, done <-chan struct{},
+	scaler scaler, band bandScaler, vertical, columns bool, threads, taps, width, height, dp, sp int,
 	dst, src []byte, cof []int16, cofscale int, off []int16) {
-	dispatch(group, threads, func() {
-		nh := height / threads
-		if nh < 1 {
-			nh = 1
+	rows, cols := getTiles(threads, width, height, columns)
+	nh := height / rows
+	nw := (width / cols) &^ (columnAlign - 1)
+	di := 0
+	si := 0
+	oi := 0
+	ci := 0
+	for i := 0; i < rows; i++ {
+		last := i+1 == rows
+		ih := nh
+		if last {
+			ih = height - nh*(rows-1)
 		}
-		di := 0
-		si := 0
-		oi := 0
-		ci := 0
-		for i := 0; i < threads; i++ {
-			last := i+1 == threads
-			ih := nh
-			if last {
-				ih = height - nh*(threads-1)
-			}
-			if ih == 0 {
-				continue
-			}
-			next := width
-			if vertical {
-				next = ih
+		next := width
+		if vertical {
+			next = ih
+		}
+		for j := 0; j < cols; j++ {
+			if canceled(done) {
+				return
 			}
-			scaleSlice(group, threads, scaler,
-				dst[di:di+dp*(ih-1)+width],
-				src[si:],
-				cof[ci:ci+next*taps*cofscale],
-				off[oi:oi+next],
-				taps, width, ih, dp, sp)
-			if last {
-				break
+			x := nw * j
+			iw := nw
+			if j+1 == cols {
+				iw = width - x
 			}
-			di += ih * dp
+			xsrc := src[si:]
+			xcof := cof[ci : ci+next*taps*cofscale]
+			xoff := off[oi : oi+next]
+			pos := x
 			if vertical {
-				ci += ih * taps * cofscale
-				for j := 0; j < ih; j++ {
-					si += sp * int(off[oi+j])
-				}
-				oi += ih
+				xsrc = src[si+x:]
+				pos = oi
 			} else {
-				si += sp * ih
+				xcof = xcof[x*taps*cofscale : (x+iw)*taps*cofscale]
+				xoff = xoff[x : x+iw]
+			}
+			t.jobs = append(t.jobs, tileJob{
+				done:     done,
+				scaler:   scaler,
+				band:     band,
+				pos:      pos,
+				vertical: vertical,
+				dst:      dst[di+x : di+dp*(ih-1)+x+iw],
+				src:      xsrc,
+				cof:      xcof,
+				cofscale: cofscale,
+				off:      xoff,
+				taps:     taps,
+				width:    iw,
+				height:   ih,
+				dp:       dp,
+				sp:       sp,
+			})
+			dispatch(pool, &t.group, threads, &t.jobs[len(t.jobs)-1])
+		}
+		if last {
+			break
+		}
+		di += ih * dp
+		if vertical {
+			ci += ih * taps * cofscale
+			for j := 0; j < ih; j++ {
+				si += sp * int(off[oi+j])
 			}
+			oi += ih
+		} else {
+			si += sp * ih
 		}
-	})
+	}
 }
 
 func (c *context) Resize(dst, src []byte, width, height, dp, sp int) {
+	// jobs run on the calling goroutine once the pool is closed
+	if pool := c.cfg.Pool; pool.enter() == nil {
+		defer pool.leave()
+	}
+	c.resize(nil, dst, src, width, height, dp, sp)
+}
+
+func (c *context) resize(done <-chan struct{}, dst, src []byte, width, height, dp, sp int) {
 	field := bin(c.cfg.Vertical && c.cfg.Interlaced)
+	// split resizes read every input row for both fields
+	sfield := field &^ bin(c.cfg.split)
 	dwidth := c.cfg.Output
 	dheight := height
 	if c.cfg.Vertical {
 		dwidth = width
 	}
 	pk := c.cfg.Pack
-	group := sync.WaitGroup{}
+	t := c.jobs.get().(*tileJobs)
+	defer c.jobs.put(t)
 	for i, k := range c.kernels[:1+field] {
 		if c.cfg.Vertical {
 			dheight = (c.cfg.Output + (1-i)*int(field)) >> field
 		}
-		scaleSlices(&group, c.scaler, c.cfg.Vertical, c.cfg.Threads,
-			k.size, dwidth*pk, dheight, dp<<field, sp<<field,
-			dst[dp*i:], src[sp*i:], k.coeffs, k.cofscale, k.offsets)
+		scaleTiles(t, c.cfg.Pool, done, c.scaler, c.band, c.cfg.Vertical, c.columns, c.cfg.Threads,
+			k.size, dwidth*pk, dheight, dp<<field, sp<<sfield,
+			dst[dp*i:], src[sp*i*int(sfield):], k.coeffs, k.cofscale, k.offsets)
 	}
-	group.Wait()
+	t.reset()
 }
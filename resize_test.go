@@ -5,16 +5,22 @@
 package rez
 
 import (
+	gocontext "context"
 	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
 	_ "image/jpeg"
 	"image/png"
 	"math"
+	"math/rand"
 	"os"
 	"reflect"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type Tester interface {
@@ -359,3 +365,1563 @@ func TestBigKernels(t *testing.T) {
 		runTestCase(t, tc, 1)
 	}
 }
+
+func testDeinterlaceWith(t *testing.T, mode DeinterlaceMode, rgb bool, psnr float64) {
+	var src, dst, ref image.Image
+	raw := readImage(t, "testdata/lenna.jpg")
+	src = raw
+	ref = image.NewYCbCr(raw.Bounds(), image.YCbCrSubsampleRatio420)
+	if rgb {
+		src = toRgb(raw)
+		ref = toRgb(ref)
+	}
+	err := Convert(ref, src, nil)
+	expect(t, err, nil)
+	dst = image.NewYCbCr(raw.Bounds(), image.YCbCrSubsampleRatio420)
+	if rgb {
+		dst = toRgb(dst)
+	}
+	for _, asm := range []bool{false, true} {
+		cfg, err := PrepareConversion(dst, src)
+		expect(t, err, nil)
+		cfg.Input.Interlaced = true
+		cfg.Deinterlace = mode
+		cfg.DisableAsm = !asm
+		converter, err := NewConverter(cfg, NewBicubicFilter())
+		expect(t, err, nil)
+		err = converter.Convert(dst, src)
+		expect(t, err, nil)
+		checkPsnrs(t, ref, dst, image.Rectangle{}, []float64{psnr, psnr, psnr})
+	}
+}
+
+func TestDeinterlace(t *testing.T) {
+	for _, rgb := range []bool{false, true} {
+		testDeinterlaceWith(t, DeinterlaceBob, rgb, 28)
+		testDeinterlaceWith(t, DeinterlaceBlend, rgb, 30)
+		testDeinterlaceWith(t, DeinterlaceEdge, rgb, 30)
+	}
+	src := readImage(t, "testdata/lenna.jpg")
+	dst := image.NewYCbCr(image.Rect(0, 0, 640, 480), image.YCbCrSubsampleRatio420)
+	cfg, err := PrepareConversion(dst, src)
+	expect(t, err, nil)
+	cfg.Input.Interlaced = true
+	cfg.Deinterlace = DeinterlaceMode(-1)
+	_, err = NewConverter(cfg, NewBicubicFilter())
+	if err == nil {
+		t.Fatalf("invalid deinterlace mode accepted")
+	}
+	cfg.Deinterlace = DeinterlaceEdge
+	converter, err := NewConverter(cfg, NewBicubicFilter())
+	expect(t, err, nil)
+	err = converter.Convert(dst, src)
+	expect(t, err, nil)
+	// progressive input into interlaced output
+	convert(t, src, dst, true, false, NewBicubicFilter())
+	cfg, err = PrepareConversion(src, dst)
+	expect(t, err, nil)
+	cfg.Output.Interlaced = true
+	converter, err = NewConverter(cfg, NewBicubicFilter())
+	expect(t, err, nil)
+	err = converter.Convert(src, dst)
+	expect(t, err, nil)
+}
+
+func TestFields(t *testing.T) {
+	src := readImage(t, "testdata/lenna.jpg").(*image.YCbCr)
+	b := src.Bounds()
+	half := image.Rect(0, 0, b.Dx(), b.Dy()/2)
+	fields := []FieldSelect{TopField, BottomField}
+	frame := image.NewYCbCr(b, src.SubsampleRatio)
+	for _, order := range []FieldOrder{TopFieldFirst, BottomFieldFirst} {
+		for _, field := range fields {
+			// extract a single field & weave it back into a frame
+			dst := image.NewYCbCr(half, src.SubsampleRatio)
+			cfg, err := PrepareConversion(dst, src)
+			expect(t, err, nil)
+			cfg.Input.Interlaced = true
+			cfg.Input.Order = order
+			cfg.Input.Field = field
+			converter, err := NewConverter(cfg, NewBicubicFilter())
+			expect(t, err, nil)
+			err = converter.Convert(dst, src)
+			expect(t, err, nil)
+			line := int(bin(field == BottomField))
+			for y := 0; y < half.Dy(); y++ {
+				expect(t, dst.Y[dst.YStride*y:dst.YStride*y+half.Dx()],
+					src.Y[src.YStride*(y*2+line):src.YStride*(y*2+line)+half.Dx()])
+			}
+			cfg, err = PrepareConversion(frame, dst)
+			expect(t, err, nil)
+			cfg.Output.Interlaced = true
+			cfg.Output.Order = order
+			cfg.Output.Field = field
+			converter, err = NewConverter(cfg, NewBicubicFilter())
+			expect(t, err, nil)
+			err = converter.Convert(frame, dst)
+			expect(t, err, nil)
+		}
+		checkPsnrs(t, src, frame, image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+		testDeinterlaceOrder(t, order)
+	}
+	cfg, err := PrepareConversion(frame, src)
+	expect(t, err, nil)
+	cfg.Input.Interlaced = true
+	cfg.Output.Interlaced = true
+	cfg.Output.Order = BottomFieldFirst
+	_, err = NewConverter(cfg, NewBicubicFilter())
+	if err == nil {
+		t.Fatalf("field order mismatch accepted")
+	}
+	cfg.Output.Order = TopFieldFirst
+	cfg.Output.Interlaced = false
+	cfg.Output.Field = TopField
+	_, err = NewConverter(cfg, NewBicubicFilter())
+	if err == nil {
+		t.Fatalf("progressive field selection accepted")
+	}
+}
+
+func TestFrameToField(t *testing.T) {
+	// top lines are black & bottom lines are white
+	src := image.NewGray(image.Rect(0, 0, 64, 48))
+	for y := 1; y < 48; y += 2 {
+		for x := 0; x < 64; x++ {
+			src.Pix[src.Stride*y+x] = 255
+		}
+	}
+	for _, field := range []FieldSelect{TopField, BottomField} {
+		dst := image.NewGray(image.Rect(0, 0, 32, 48))
+		cfg, err := PrepareConversion(dst, src)
+		expect(t, err, nil)
+		cfg.Input.Interlaced = true
+		cfg.Output.Interlaced = true
+		cfg.Output.Field = field
+		_, err = NewConverter(cfg, NewBicubicFilter())
+		if err == nil {
+			t.Fatalf("interlaced frame into %v accepted", field)
+		}
+		// the matching input field resizes without blending fields
+		cfg.Input.Field = field
+		converter, err := NewConverter(cfg, NewBicubicFilter())
+		expect(t, err, nil)
+		err = converter.Convert(dst, src)
+		expect(t, err, nil)
+		want := byte(255 * bin(field == BottomField))
+		line := int(bin(field == BottomField))
+		for y := line; y < 48; y += 2 {
+			for x, v := range dst.Pix[dst.Stride*y : dst.Stride*y+32] {
+				if v != want {
+					t.Fatalf("invalid %v pixel %v,%v = %v", field, x, y, v)
+				}
+			}
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	// vertical ramps keep their slope in both fields, on every plane
+	src := image.NewYCbCr(image.Rect(0, 0, 64, 48), image.YCbCrSubsampleRatio420)
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			src.Y[src.YStride*y+x] = byte(y * 4)
+		}
+	}
+	for y := 0; y < 24; y++ {
+		for x := 0; x < 32; x++ {
+			src.Cb[src.CStride*y+x] = byte(y * 8)
+			src.Cr[src.CStride*y+x] = byte(255 - y*8)
+		}
+	}
+	for _, asm := range []bool{false, true} {
+		dst := image.NewYCbCr(src.Bounds(), image.YCbCrSubsampleRatio420)
+		cfg, err := PrepareConversion(dst, src)
+		expect(t, err, nil)
+		cfg.Output.Interlaced = true
+		cfg.DisableAsm = !asm
+		converter, err := NewConverter(cfg, NewBicubicFilter())
+		expect(t, err, nil)
+		err = converter.Convert(dst, src)
+		expect(t, err, nil)
+		for y := 4; y < 44; y++ {
+			expectNear(t, dst.Y[dst.YStride*y:dst.YStride*y+1], []byte{byte(y * 4)})
+		}
+		for y := 2; y < 22; y++ {
+			expectNear(t, dst.Cb[dst.CStride*y:dst.CStride*y+1], []byte{byte(y * 8)})
+			expectNear(t, dst.Cr[dst.CStride*y:dst.CStride*y+1], []byte{byte(255 - y*8)})
+		}
+	}
+	// single line stripes are filtered out of both fields
+	gray := image.NewGray(image.Rect(0, 0, 64, 48))
+	for y := 1; y < 48; y += 2 {
+		for x := 0; x < 64; x++ {
+			gray.Pix[gray.Stride*y+x] = 255
+		}
+	}
+	dst := image.NewGray(gray.Bounds())
+	cfg, err := PrepareConversion(dst, gray)
+	expect(t, err, nil)
+	cfg.Output.Interlaced = true
+	converter, err := NewConverter(cfg, NewBicubicFilter())
+	expect(t, err, nil)
+	err = converter.Convert(dst, gray)
+	expect(t, err, nil)
+	for y := 4; y < 44; y++ {
+		if v := dst.Pix[dst.Stride*y]; v < 96 || v > 160 {
+			t.Fatalf("stripe row %v kept at %v", y, v)
+		}
+	}
+}
+
+func testDeinterlaceOrder(t *testing.T, order FieldOrder) {
+	src := readImage(t, "testdata/lenna.jpg")
+	dst := image.NewYCbCr(src.Bounds(), image.YCbCrSubsampleRatio420)
+	for _, mode := range []DeinterlaceMode{DeinterlaceBob, DeinterlaceBlend, DeinterlaceEdge} {
+		cfg, err := PrepareConversion(dst, src)
+		expect(t, err, nil)
+		cfg.Input.Interlaced = true
+		cfg.Input.Order = order
+		cfg.Deinterlace = mode
+		converter, err := NewConverter(cfg, NewBicubicFilter())
+		expect(t, err, nil)
+		err = converter.Convert(dst, src)
+		expect(t, err, nil)
+		checkPsnrs(t, src, dst, image.Rectangle{}, []float64{28, 28, 28})
+	}
+}
+
+func TestDeinterlaceKeptField(t *testing.T) {
+	raw := toRgb(readImage(t, "testdata/lenna.jpg"))
+	for _, height := range []int{48, 37, 3} {
+		src := image.NewRGBA(image.Rect(0, 0, 64, height))
+		draw.Draw(src, src.Bounds(), raw, image.ZP, draw.Src)
+		for _, order := range []FieldOrder{TopFieldFirst, BottomFieldFirst} {
+			first := int(bin(order == BottomFieldFirst))
+			for _, mode := range []DeinterlaceMode{DeinterlaceBob, DeinterlaceEdge} {
+				for _, asm := range []bool{false, true} {
+					dst := image.NewRGBA(src.Bounds())
+					cfg, err := PrepareConversion(dst, src)
+					expect(t, err, nil)
+					cfg.Input.Interlaced = true
+					cfg.Input.Order = order
+					cfg.Deinterlace = mode
+					cfg.DisableAsm = !asm
+					converter, err := NewConverter(cfg, NewBicubicFilter())
+					expect(t, err, nil)
+					err = converter.Convert(dst, src)
+					expect(t, err, nil)
+					for y := first; y < height; y += 2 {
+						expect(t, dst.Pix[dst.Stride*y:dst.Stride*y+64*4],
+							src.Pix[src.Stride*y:src.Stride*y+64*4])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestBobPhase(t *testing.T) {
+	// missing lines of a vertical ramp must land between kept lines
+	src := image.NewGray(image.Rect(0, 0, 32, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 32; x++ {
+			src.Pix[src.Stride*y+x] = byte(y * 4)
+		}
+	}
+	for _, order := range []FieldOrder{TopFieldFirst, BottomFieldFirst} {
+		dst := image.NewGray(src.Bounds())
+		cfg, err := PrepareConversion(dst, src)
+		expect(t, err, nil)
+		cfg.Input.Interlaced = true
+		cfg.Input.Order = order
+		cfg.Deinterlace = DeinterlaceBob
+		converter, err := NewConverter(cfg, NewBilinearFilter())
+		expect(t, err, nil)
+		err = converter.Convert(dst, src)
+		expect(t, err, nil)
+		for y := 1; y < 47; y++ {
+			expect(t, dst.Pix[dst.Stride*y], byte(y*4))
+		}
+	}
+}
+
+func TestNewFilters(t *testing.T) {
+	for _, f := range []Filter{
+		NewBoxFilter(),
+		NewNearestFilter(),
+		NewHermiteFilter(),
+		NewGaussianFilter(0.5),
+		NewGaussianFilter(1.5),
+	} {
+		for _, ii := range []bool{false, true} {
+			tc := NewTestCase(256, 256, ii)
+			tc.filter = f
+			tc.psnrs = []float64{22, 30, 30}
+			runTestCase(t, tc, 1)
+		}
+	}
+	// nearest must never blend pixels
+	src := readImage(t, "testdata/gray.png").(*image.Gray)
+	b := src.Bounds()
+	big := image.NewGray(image.Rect(0, 0, b.Dx()*2, b.Dy()*2))
+	err := Convert(big, src, NewNearestFilter())
+	expect(t, err, nil)
+	for y := 0; y < b.Dy()*2; y++ {
+		for x := 0; x < b.Dx()*2; x++ {
+			expect(t, big.GrayAt(x, y), src.GrayAt(x>>1, y>>1))
+		}
+	}
+	dst := image.NewGray(b)
+	err = Convert(dst, big, NewNearestFilter())
+	expect(t, err, nil)
+	checkPsnrs(t, src, dst, image.Rectangle{}, []float64{math.Inf(1)})
+	// box is an area average on integer downscales
+	err = Convert(dst, big, NewBoxFilter())
+	expect(t, err, nil)
+	checkPsnrs(t, src, dst, image.Rectangle{}, []float64{math.Inf(1)})
+	// small sigmas keep the nearest input pixel on upscales
+	for _, sigma := range []float64{0.1, 0.01} {
+		expectFlatUpscales(t, NewGaussianFilter(sigma))
+	}
+	// gaussian needs a positive sigma
+	for _, sigma := range []float64{0, -1, math.NaN()} {
+		expectPanic(t, func() { NewGaussianFilter(sigma) })
+	}
+}
+
+// expectPanic fails unless fn panics
+func expectPanic(t *testing.T, fn func()) {
+	defer func() {
+		if recover() == nil {
+			_, file, line, _ := runtime.Caller(3)
+			t.Fatalf("%v:%v expected panic", file, line)
+		}
+	}()
+	fn()
+}
+
+func TestWindowedSincFilters(t *testing.T) {
+	windows := []Window{
+		NewKaiserWindow(4),
+		NewBlackmanWindow(),
+		NewBlackmanHarrisWindow(),
+		NewHannWindow(),
+		NewHammingWindow(),
+	}
+	list := []Filter{
+		NewSpline16Filter(),
+		NewSpline36Filter(),
+		NewSpline64Filter(),
+	}
+	for _, w := range windows {
+		list = append(list, NewWindowedSincFilter(w, 3))
+	}
+	for _, f := range list {
+		// interpolating filters are 1 at 0 and 0 on other integers
+		expect(t, math.Abs(f.Get(0)-1) < 1e-9, true)
+		for i := 1; i <= f.Taps(); i++ {
+			expect(t, math.Abs(f.Get(float64(i))) < 1e-9, true)
+		}
+		tc := NewTestCase(256, 256, false)
+		tc.filter = f
+		tc.psnrs = []float64{22, 30, 30}
+		runTestCase(t, tc, 1)
+	}
+}
+
+func TestBlurFilter(t *testing.T) {
+	for _, vertical := range []bool{false, true} {
+		cfg := ResizerConfig{
+			Input:    64,
+			Output:   27,
+			Vertical: vertical,
+			Pack:     1,
+		}
+		ref := makeKernel(&cfg, NewBicubicFilter(), 0)
+		same := makeKernel(&cfg, NewBlurFilter(NewBicubicFilter(), 1), 0)
+		expect(t, same, ref)
+		wide := makeKernel(&cfg, NewBlurFilter(NewBicubicFilter(), 1.5), 0)
+		expect(t, wide.size > ref.size, true)
+		thin := makeKernel(&cfg, NewBlurFilter(NewBicubicFilter(), 0.6), 0)
+		expect(t, thin.size < ref.size, true)
+	}
+	f := NewBlurFilter(NewLanczosFilter(3), 0.75)
+	expect(t, f.Taps(), 3)
+	expect(t, f.(FractionalFilter).Support(), 2.25)
+	tc := NewTestCase(256, 256, false)
+	tc.filter = NewBlurFilter(NewGaussianFilter(0.5), 0.8)
+	tc.psnrs = []float64{22, 30, 30}
+	runTestCase(t, tc, 1)
+	// wrapped nearest filters must never blend pixels
+	src := image.NewGray(image.Rect(0, 0, 37, 29))
+	for i := range src.Pix {
+		src.Pix[i] = byte(255 * (i % 3 & 1))
+	}
+	for _, f := range []Filter{
+		NewBlurFilter(NewNearestFilter(), 0.6),
+		NewBlurFilter(NewNearestFilter(), 1.5),
+		SampleFilter(NewNearestFilter(), 64),
+		SampleFilter(NewBlurFilter(NewNearestFilter(), 2), 64),
+	} {
+		for _, size := range []image.Rectangle{
+			image.Rect(0, 0, 80, 61),
+			image.Rect(0, 0, 13, 11),
+		} {
+			dst := image.NewGray(size)
+			err := Convert(dst, src, f)
+			expect(t, err, nil)
+			for _, v := range dst.Pix {
+				if v != 0 && v != 255 {
+					t.Fatalf("%v blended pixels into %v", f.Name(), v)
+				}
+			}
+		}
+	}
+}
+
+// expectFlatUpscales fails unless upscales of a flat image with f keep every
+// pixel unchanged
+func expectFlatUpscales(t *testing.T, f Filter) {
+	src := image.NewGray(image.Rect(0, 0, 10, 10))
+	for i := range src.Pix {
+		src.Pix[i] = 200
+	}
+	for _, asm := range []bool{false, true} {
+		dst := image.NewGray(image.Rect(0, 0, 37, 37))
+		cfg, err := PrepareConversion(dst, src)
+		expect(t, err, nil)
+		cfg.DisableAsm = !asm
+		converter, err := NewConverter(cfg, f)
+		expect(t, err, nil)
+		err = converter.Convert(dst, src)
+		expect(t, err, nil)
+		for y := 0; y < 37; y++ {
+			for x, v := range dst.Pix[dst.Stride*y : dst.Stride*y+37] {
+				if v != 200 {
+					t.Fatalf("%v changed pixel %v,%v into %v", f.Name(), x, y, v)
+				}
+			}
+		}
+	}
+}
+
+func TestNarrowUpscales(t *testing.T) {
+	// every output pixel keeps at least one tap
+	for _, f := range []Filter{
+		NewBlurFilter(NewBicubicFilter(), 0.5),
+		NewBlurFilter(NewBoxFilter(), 0.5),
+		NewBlurFilter(NewBilinearFilter(), 0.2),
+	} {
+		expectFlatUpscales(t, f)
+	}
+}
+
+func TestTableFilter(t *testing.T) {
+	f := NewTableFilter([]float64{1, 0.5, 0}, 1)
+	expect(t, f.Taps(), 1)
+	expect(t, f.Get(0.25)/f.Get(0), 0.75)
+	expect(t, f.Get(1), 0.0)
+	// a unit area triangle peaks at 1
+	expect(t, math.Abs(f.Get(0)-1) < 1e-9, true)
+	for _, ref := range []Filter{
+		NewBicubicFilter(),
+		NewLanczosFilter(3),
+		NewGaussianFilter(0.7),
+	} {
+		raw := readImage(t, "testdata/lenna.jpg")
+		a := image.NewYCbCr(image.Rect(0, 0, 333, 201), image.YCbCrSubsampleRatio420)
+		b := image.NewYCbCr(a.Bounds(), image.YCbCrSubsampleRatio420)
+		err := Convert(a, raw, ref)
+		expect(t, err, nil)
+		err = Convert(b, raw, SampleFilter(ref, 4096))
+		expect(t, err, nil)
+		checkPsnrs(t, a, b, image.Rectangle{}, []float64{50, 50, 50})
+	}
+}
+
+func TestPlaneFilters(t *testing.T) {
+	src := readImage(t, "testdata/lenna.jpg")
+	dst := image.NewYCbCr(image.Rect(0, 0, 301, 199), image.YCbCrSubsampleRatio420)
+	sharp := image.NewYCbCr(dst.Bounds(), dst.SubsampleRatio)
+	soft := image.NewYCbCr(dst.Bounds(), dst.SubsampleRatio)
+	mixed := image.NewYCbCr(dst.Bounds(), dst.SubsampleRatio)
+	err := Convert(sharp, src, NewLanczosFilter(3))
+	expect(t, err, nil)
+	err = Convert(soft, src, NewBilinearFilter())
+	expect(t, err, nil)
+	cfg, err := PrepareConversion(dst, src)
+	expect(t, err, nil)
+	cfg.Filters[1] = PlaneFilters{NewBilinearFilter(), NewBilinearFilter()}
+	cfg.Filters[2] = PlaneFilters{Horizontal: NewBilinearFilter(), Vertical: NewBilinearFilter()}
+	converter, err := NewConverter(cfg, NewLanczosFilter(3))
+	expect(t, err, nil)
+	err = converter.Convert(mixed, src)
+	expect(t, err, nil)
+	expect(t, mixed.Y, sharp.Y)
+	expect(t, mixed.Cb, soft.Cb)
+	expect(t, mixed.Cr, soft.Cr)
+	// horizontal & vertical filters are independent
+	cfg.Filters[0] = PlaneFilters{Vertical: NewBilinearFilter()}
+	converter, err = NewConverter(cfg, NewLanczosFilter(3))
+	expect(t, err, nil)
+	err = converter.Convert(mixed, src)
+	expect(t, err, nil)
+	if reflect.DeepEqual(mixed.Y, sharp.Y) || reflect.DeepEqual(mixed.Y, soft.Y) {
+		t.Fatalf("vertical filter ignored")
+	}
+}
+
+func TestAntiRinging(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			src.Pix[y*src.Stride+x] = byte(64 + 128*((x/8+y/8)&1))
+		}
+	}
+	dst := image.NewGray(image.Rect(0, 0, 147, 133))
+	for _, asm := range []bool{false, true} {
+		for _, strength := range []float64{0, 1} {
+			cfg, err := PrepareConversion(dst, src)
+			expect(t, err, nil)
+			cfg.DisableAsm = !asm
+			cfg.AntiRinging = strength
+			converter, err := NewConverter(cfg, NewLanczosFilter(3))
+			expect(t, err, nil)
+			err = converter.Convert(dst, src)
+			expect(t, err, nil)
+			rings := false
+			for _, v := range dst.Pix {
+				rings = rings || v < 64 || v > 192
+			}
+			expect(t, rings, strength == 0)
+		}
+	}
+}
+
+func laplacian(img *image.Gray) int {
+	sum := 0
+	b := img.Bounds()
+	for y := b.Min.Y + 1; y < b.Max.Y-1; y++ {
+		for x := b.Min.X + 1; x < b.Max.X-1; x++ {
+			v := 4*int(img.GrayAt(x, y).Y) -
+				int(img.GrayAt(x-1, y).Y) - int(img.GrayAt(x+1, y).Y) -
+				int(img.GrayAt(x, y-1).Y) - int(img.GrayAt(x, y+1).Y)
+			if v < 0 {
+				v = -v
+			}
+			sum += v
+		}
+	}
+	return sum
+}
+
+func TestSharpen(t *testing.T) {
+	src := readImage(t, "testdata/gray.png")
+	ref := image.NewGray(image.Rect(0, 0, 97, 83))
+	err := Convert(ref, src, NewBicubicFilter())
+	expect(t, err, nil)
+	for _, asm := range []bool{false, true} {
+		for _, s := range []Sharpen{
+			{Amount: 0.8, Radius: 1},
+			{Amount: 0.8, Radius: 1, Threshold: 256},
+		} {
+			dst := image.NewGray(ref.Bounds())
+			cfg, err := PrepareConversion(dst, src)
+			expect(t, err, nil)
+			cfg.DisableAsm = !asm
+			cfg.Sharpen = s
+			converter, err := NewConverter(cfg, NewBicubicFilter())
+			expect(t, err, nil)
+			err = converter.Convert(dst, src)
+			expect(t, err, nil)
+			if s.Threshold > 255 {
+				expect(t, dst.Pix, ref.Pix)
+			} else if laplacian(dst) <= laplacian(ref) {
+				t.Fatalf("sharpen did not sharpen")
+			}
+		}
+	}
+	cfg, err := PrepareConversion(ref, src)
+	expect(t, err, nil)
+	cfg.Sharpen = Sharpen{Amount: 1}
+	_, err = NewConverter(cfg, NewBicubicFilter())
+	if err == nil {
+		t.Fatalf("invalid sharpen radius accepted")
+	}
+}
+
+// externalSharpener applies an unsharp mask on resized planes with full
+// plane passes
+type externalSharpener struct {
+	hrez, wrez Resizer
+	tmp, blur  []byte
+	amount     int
+	threshold  int
+}
+
+func newExternalSharpener(p *Plane, s *Sharpen, interlaced, asm bool, threads int) *externalSharpener {
+	filter := NewGaussianFilter(s.Radius)
+	return &externalSharpener{
+		hrez: NewResize(&ResizerConfig{
+			Input:      p.Height,
+			Output:     p.Height,
+			Vertical:   true,
+			Interlaced: interlaced,
+			Pack:       p.Pack,
+			Threads:    threads,
+			DisableAsm: !asm,
+		}, filter),
+		wrez: NewResize(&ResizerConfig{
+			Input:      p.Width,
+			Output:     p.Width,
+			Pack:       p.Pack,
+			Threads:    threads,
+			DisableAsm: !asm,
+		}, filter),
+		tmp:       make([]byte, len(p.Data)),
+		blur:      make([]byte, len(p.Data)),
+		amount:    int(s.Amount*(1<<Bits) + 0.5),
+		threshold: s.Threshold,
+	}
+}
+
+func (e *externalSharpener) sharpen(p *Plane) {
+	e.hrez.Resize(e.tmp, p.Data, p.Width, p.Height, p.Pitch, p.Pitch)
+	e.wrez.Resize(e.blur, e.tmp, p.Width, p.Height, p.Pitch, p.Pitch)
+	di := 0
+	for y := 0; y < p.Height; y++ {
+		d := p.Data[di : di+p.Width*p.Pack]
+		for x, v := range e.blur[di : di+len(d)] {
+			diff := int(d[x]) - int(v)
+			if diff >= e.threshold || -diff >= e.threshold {
+				d[x] = u8(int(d[x]) + (diff*e.amount+1<<(Bits-1))>>Bits)
+			}
+		}
+		di += p.Pitch
+	}
+}
+
+func TestSharpenRows(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	sharpen := Sharpen{Amount: 0.8, Radius: 1.5, Threshold: 2}
+	sizes := []image.Point{{160, 92}, {640, 360}, {320, 180}, {37, 20}}
+	for _, img := range []image.Image{src, toRgb(src)} {
+		for _, size := range sizes {
+			for _, interlaced := range []bool{false, true} {
+				for _, rows := range []int{0, 7} {
+					for _, asm := range []bool{false, true} {
+						ref, err := newImageLike(img, size.X, size.Y)
+						expect(t, err, nil)
+						out, err := newImageLike(img, size.X, size.Y)
+						expect(t, err, nil)
+						cfg, err := PrepareConversion(ref, img)
+						expect(t, err, nil)
+						cfg.Input.Interlaced = interlaced
+						cfg.Output.Interlaced = interlaced
+						cfg.DisableAsm = !asm
+						cfg.StripRows = rows
+						cfg.Threads = 3
+						converter, err := NewConverter(cfg, NewBicubicFilter())
+						expect(t, err, nil)
+						err = converter.Convert(ref, img)
+						expect(t, err, nil)
+						_, planes, err := inspect(ref, interlaced)
+						expect(t, err, nil)
+						for i := range planes {
+							p := &planes[i]
+							newExternalSharpener(p, &sharpen, interlaced, asm, 1).sharpen(p)
+						}
+						cfg.Sharpen = sharpen
+						converter, err = NewConverter(cfg, NewBicubicFilter())
+						expect(t, err, nil)
+						err = converter.Convert(out, img)
+						expect(t, err, nil)
+						checkPsnrs(t, ref, out, image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMultiResize(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 2048, 1999), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	ref := image.NewYCbCr(image.Rect(0, 0, 91, 67), image.YCbCrSubsampleRatio420)
+	err = Convert(ref, src, NewBicubicFilter())
+	expect(t, err, nil)
+	for _, asm := range []bool{false, true} {
+		for _, ratio := range []float64{2, 3, 8} {
+			dst := image.NewYCbCr(ref.Bounds(), ref.SubsampleRatio)
+			cfg, err := PrepareConversion(dst, src)
+			expect(t, err, nil)
+			cfg.DisableAsm = !asm
+			cfg.MaxFilterRatio = ratio
+			converter, err := NewConverter(cfg, NewBicubicFilter())
+			expect(t, err, nil)
+			err = converter.Convert(dst, src)
+			expect(t, err, nil)
+			checkPsnrs(t, ref, dst, image.Rectangle{}, []float64{30, 38, 38})
+		}
+	}
+	cfg := ResizerConfig{Input: 2048, Output: 91, MaxFilterRatio: 3}
+	m, ok := NewResize(&cfg, NewBicubicFilter()).(*multiContext)
+	expect(t, ok, true)
+	expect(t, m.sizes, []int{1024, 512, 256, 91})
+	cfg.MaxFilterRatio = 32
+	_, ok = NewResize(&cfg, NewBicubicFilter()).(*context)
+	expect(t, ok, true)
+}
+
+// expectNear fails unless a & b differ by at most one per byte
+func expectNear(t *testing.T, a, b []byte) {
+	for i, v := range a {
+		if d := int(v) - int(b[i]); d > 1 || d < -1 {
+			t.Fatalf("byte %v: %v != %v", i, v, b[i])
+		}
+	}
+}
+
+func TestFastRatios(t *testing.T) {
+	ratios := []struct{ in, out int }{{2, 1}, {4, 1}, {1, 2}}
+	list := []Filter{
+		NewBilinearFilter(),
+		NewBicubicFilter(),
+		NewLanczosFilter(3),
+		NewLanczosFilter(5),
+	}
+	for _, r := range ratios {
+		for _, f := range list {
+			for n := 3; n < 70; n += 5 {
+				for _, vertical := range []bool{false, true} {
+					for _, pack := range []int{1, 2, 4} {
+						for _, asm := range []bool{false, true} {
+							cfg := ResizerConfig{
+								Input:      n * r.in,
+								Output:     n * r.out,
+								Vertical:   vertical,
+								Pack:       pack,
+								Threads:    9,
+								DisableAsm: !asm,
+							}
+							k := makeKernel(&cfg, f, 0)
+							fast := makeFastKernel(&cfg, &k, asm)
+							if cfg.Output > 4 && fast == nil {
+								t.Fatalf("missing fast path %v->%v", cfg.Input, cfg.Output)
+							}
+							generic := cfg
+							generic.DisableAsm = true
+							k = makeKernel(&generic, f, 0)
+							// few rows split horizontal passes in column tiles
+							w, h := cfg.Input, 2
+							sw, dw, dh := cfg.Input*pack, cfg.Output*pack, h
+							if vertical {
+								w, h = 37, cfg.Input
+								sw, dw, dh = w*pack, w*pack, cfg.Output
+							}
+							sp := sw + 7
+							dp := dw + 5
+							src := make([]byte, sp*h)
+							for i := range src {
+								src[i] = byte(i * 7919 >> 3)
+							}
+							ref := make([]byte, dp*dh)
+							dst := make([]byte, dp*dh)
+							if vertical {
+								v8scaleNGo(ref, src, k.raw, k.offsets, k.size, dw, dh, dp, sp)
+							} else {
+								h8scaleNGo(ref, src, k.raw, k.offsets, k.size, dw, dh, dp, sp)
+							}
+							NewResize(&cfg, f).Resize(dst, src, w, h, dp, sp)
+							expectNear(t, dst, ref)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPyramid(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 301, 199), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	for _, img := range []image.Image{src, toRgb(src)} {
+		levels, err := NewPyramidImages(img, 0)
+		expect(t, err, nil)
+		sizes := []image.Point{}
+		for _, level := range levels {
+			sizes = append(sizes, level.Bounds().Size())
+		}
+		want := []image.Point{{151, 100}, {76, 50}, {38, 25}, {19, 13}, {10, 7}, {5, 4},
+			{3, 2}, {2, 1}, {1, 1}}
+		expect(t, sizes, want)
+		pyramid, err := NewPyramid(levels, img, NewBilinearFilter())
+		expect(t, err, nil)
+		err = pyramid.Convert(levels, img)
+		expect(t, err, nil)
+		ref, err := newImageLike(img, 151, 100)
+		expect(t, err, nil)
+		err = Convert(ref, img, NewBilinearFilter())
+		expect(t, err, nil)
+		checkPsnrs(t, ref, levels[0], image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+		err = pyramid.Convert(levels[:2], img)
+		if err == nil {
+			t.Fatalf("invalid level count accepted")
+		}
+	}
+	levels, err := NewPyramidImages(src, 2)
+	expect(t, err, nil)
+	expect(t, len(levels), 2)
+	err = ConvertPyramid(levels, src, NewBicubicFilter())
+	expect(t, err, nil)
+	// tiny levels average every plane down to 1x1
+	flat := NewYCbCr(image.Rect(0, 0, 7, 5), image.YCbCrSubsampleRatio420)
+	for i := range flat.Y {
+		flat.Y[i] = 90
+	}
+	for i := range flat.Cb {
+		flat.Cb[i] = 40
+		flat.Cr[i] = 210
+	}
+	levels, err = NewPyramidImages(flat, 0)
+	expect(t, err, nil)
+	expect(t, len(levels), 3)
+	err = ConvertPyramid(levels, flat, NewBicubicFilter())
+	expect(t, err, nil)
+	for _, level := range levels {
+		img := level.(*image.YCbCr)
+		expect(t, img.YStride%16, 0)
+		b := img.Bounds()
+		for y := 0; y < b.Dy(); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				expect(t, img.YCbCrAt(x, y), color.YCbCr{Y: 90, Cb: 40, Cr: 210})
+			}
+		}
+	}
+	_, err = newAverager(&Descriptor{Width: 1, Height: 1}, &Descriptor{Width: 3, Height: 3})
+	if err == nil {
+		t.Fatalf("invalid average size accepted")
+	}
+}
+
+func TestMultiConverter(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	sizes := []image.Point{{256, 144}, {192, 144}, {160, 88}, {320, 88}, {320, 180}, {64, 36}}
+	for _, img := range []image.Image{src, toRgb(src)} {
+		for _, interlaced := range []bool{false, true} {
+			for _, threads := range []int{1, 3} {
+				cfg, err := PrepareConversion(img, img)
+				expect(t, err, nil)
+				cfg.Input.Interlaced = interlaced
+				cfg.Deinterlace = DeinterlaceBlend
+				cfg.Threads = threads
+				outputs := []image.Image{}
+				descriptors := []Descriptor{}
+				for i, size := range sizes {
+					dst, err := newImageLike(img, size.X, size.Y)
+					expect(t, err, nil)
+					d, _, err := inspect(dst, interlaced && i%2 == 0)
+					expect(t, err, nil)
+					outputs = append(outputs, dst)
+					descriptors = append(descriptors, *d)
+				}
+				converter, err := NewMultiConverter(cfg, descriptors, NewBicubicFilter())
+				expect(t, err, nil)
+				err = converter.Convert(outputs, img)
+				expect(t, err, nil)
+				for i, output := range outputs {
+					ref, err := newImageLike(img, sizes[i].X, sizes[i].Y)
+					expect(t, err, nil)
+					c := *cfg
+					c.Output = descriptors[i]
+					single, err := NewConverter(&c, NewBicubicFilter())
+					expect(t, err, nil)
+					err = single.Convert(ref, img)
+					expect(t, err, nil)
+					checkPsnrs(t, ref, output, image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+				}
+				err = converter.Convert(outputs[:2], img)
+				if err == nil {
+					t.Fatalf("invalid output count accepted")
+				}
+			}
+		}
+	}
+}
+
+func TestStripRows(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	sizes := []image.Point{{160, 90}, {640, 360}, {100, 300}, {37, 17}}
+	for _, img := range []image.Image{src, toRgb(src)} {
+		for _, size := range sizes {
+			for _, rows := range []int{1, 7, 64} {
+				for _, asm := range []bool{false, true} {
+					ref, err := newImageLike(img, size.X, size.Y)
+					expect(t, err, nil)
+					out, err := newImageLike(img, size.X, size.Y)
+					expect(t, err, nil)
+					cfg, err := PrepareConversion(ref, img)
+					expect(t, err, nil)
+					cfg.DisableAsm = !asm
+					cfg.AntiRinging = 0.5
+					cfg.Threads = 3
+					converter, err := NewConverter(cfg, NewLanczosFilter(3))
+					expect(t, err, nil)
+					err = converter.Convert(ref, img)
+					expect(t, err, nil)
+					cfg.StripRows = rows
+					converter, err = NewConverter(cfg, NewLanczosFilter(3))
+					expect(t, err, nil)
+					err = converter.Convert(out, img)
+					expect(t, err, nil)
+					checkPsnrs(t, ref, out, image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+				}
+			}
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 640, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	for _, img := range []image.Image{src, toRgb(src)} {
+		dst, err := newImageLike(img, 64, 360)
+		expect(t, err, nil)
+		cfg, err := PrepareConversion(dst, img)
+		expect(t, err, nil)
+		converter, err := NewConverter(cfg, NewBicubicFilter())
+		expect(t, err, nil)
+		plan := converter.(Planner).Plan()
+		expect(t, len(plan), cfg.Output.Planes)
+		expect(t, plan[0].Order, HorizontalFirst)
+		expect(t, plan[0].Width, 64)
+		expect(t, plan[0].Height, 180)
+		err = converter.Convert(dst, img)
+		expect(t, err, nil)
+		// horizontal first must match two single pass conversions
+		tmp, err := newImageLike(img, 64, 180)
+		expect(t, err, nil)
+		err = Convert(tmp, img, NewBicubicFilter())
+		expect(t, err, nil)
+		ref, err := newImageLike(img, 64, 360)
+		expect(t, err, nil)
+		err = Convert(ref, tmp, NewBicubicFilter())
+		expect(t, err, nil)
+		checkPsnrs(t, ref, dst, image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+		// vertical first is kept for the opposite resize
+		back, err := newImageLike(img, 640, 180)
+		expect(t, err, nil)
+		cfg, err = PrepareConversion(back, dst)
+		expect(t, err, nil)
+		converter, err = NewConverter(cfg, NewBicubicFilter())
+		expect(t, err, nil)
+		plan = converter.(Planner).Plan()
+		expect(t, plan[0].Order, VerticalFirst)
+		expect(t, plan[0].Width, 64)
+		expect(t, plan[0].Height, 180)
+	}
+}
+
+func TestPool(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	sizes := []image.Point{{160, 90}, {640, 360}, {100, 300}}
+	for _, workers := range []int{1, 2, 0} {
+		pool := NewPool(workers)
+		var converter Converter
+		var dst image.Image
+		for _, img := range []image.Image{src, toRgb(src)} {
+			refs := []image.Image{}
+			outs := []image.Image{}
+			converters := []Converter{}
+			for _, size := range sizes {
+				ref, err := newImageLike(img, size.X, size.Y)
+				expect(t, err, nil)
+				err = Convert(ref, img, NewBicubicFilter())
+				expect(t, err, nil)
+				out, err := newImageLike(img, size.X, size.Y)
+				expect(t, err, nil)
+				cfg, err := PrepareConversion(out, img)
+				expect(t, err, nil)
+				cfg.Pool = pool
+				converter, err := NewConverter(cfg, NewBicubicFilter())
+				expect(t, err, nil)
+				refs = append(refs, ref)
+				outs = append(outs, out)
+				converters = append(converters, converter)
+			}
+			// converters sharing one pool run concurrently
+			errs := make(chan error, len(converters))
+			for i, converter := range converters {
+				go func(i int, converter Converter) {
+					errs <- converter.Convert(outs[i], img)
+				}(i, converter)
+			}
+			for range converters {
+				expect(t, <-errs, nil)
+			}
+			for i := range refs {
+				checkPsnrs(t, refs[i], outs[i], image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+			}
+			// pools are a concurrency budget for callers & workers
+			active := int32(0)
+			peak := int32(0)
+			group := sync.WaitGroup{}
+			for i := 0; i < 8; i++ {
+				group.Add(1)
+				go func() {
+					defer group.Done()
+					expect(t, pool.enter(), nil)
+					defer pool.leave()
+					jobs := sync.WaitGroup{}
+					for j := 0; j < 8; j++ {
+						dispatch(pool, &jobs, 8, taskFunc(func() {
+							n := atomic.AddInt32(&active, 1)
+							for {
+								p := atomic.LoadInt32(&peak)
+								if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+									break
+								}
+							}
+							time.Sleep(time.Millisecond)
+							atomic.AddInt32(&active, -1)
+						}))
+					}
+					jobs.Wait()
+				}()
+			}
+			group.Wait()
+			expect(t, int(peak) <= cap(pool.slots), true)
+			converter, dst = converters[0], outs[0]
+		}
+		pool.Close()
+		pool.Close()
+		// closed pools fail instead of panicking
+		expect(t, converter.Convert(dst, toRgb(src)), errPoolClosed)
+		cfg, err := PrepareConversion(dst, toRgb(src))
+		expect(t, err, nil)
+		cfg.Pool = pool
+		_, err = NewConverter(cfg, NewBicubicFilter())
+		expect(t, err, errPoolClosed)
+	}
+}
+
+func TestConcurrentConvert(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	for _, img := range []image.Image{src, toRgb(src)} {
+		for _, rows := range []int{0, 8} {
+			dst, err := newImageLike(img, 64, 300)
+			expect(t, err, nil)
+			cfg, err := PrepareConversion(dst, img)
+			expect(t, err, nil)
+			cfg.Input.Interlaced = true
+			cfg.Deinterlace = DeinterlaceBob
+			cfg.Sharpen = Sharpen{Amount: 0.5, Radius: 1}
+			cfg.MaxFilterRatio = 2
+			cfg.StripRows = rows
+			converter, err := NewConverter(cfg, NewBicubicFilter())
+			expect(t, err, nil)
+			err = converter.Convert(dst, img)
+			expect(t, err, nil)
+			multi, err := NewMultiConverter(cfg, []Descriptor{cfg.Output, cfg.Output}, NewBicubicFilter())
+			expect(t, err, nil)
+			errs := make(chan error)
+			outs := [][]image.Image{}
+			for i := 0; i < 4; i++ {
+				out := []image.Image{}
+				for j := 0; j < 3; j++ {
+					img, err := newImageLike(img, 64, 300)
+					expect(t, err, nil)
+					out = append(out, img)
+				}
+				outs = append(outs, out)
+				go func(out []image.Image) {
+					errs <- converter.Convert(out[0], img)
+				}(out)
+				go func(out []image.Image) {
+					errs <- multi.Convert(out[1:], img)
+				}(out)
+			}
+			for range outs {
+				expect(t, <-errs, nil)
+				expect(t, <-errs, nil)
+			}
+			for _, out := range outs {
+				for _, v := range out {
+					checkPsnrs(t, dst, v, image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+				}
+			}
+		}
+	}
+}
+
+func TestConvertContext(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	c, cancel := gocontext.WithCancel(gocontext.Background())
+	for _, img := range []image.Image{src, toRgb(src)} {
+		for _, interlaced := range []bool{false, true} {
+			ref, err := newImageLike(img, 100, 300)
+			expect(t, err, nil)
+			dst, err := newImageLike(img, 100, 300)
+			expect(t, err, nil)
+			cfg, err := PrepareConversion(dst, img)
+			expect(t, err, nil)
+			cfg.Input.Interlaced = interlaced
+			cfg.Output.Interlaced = interlaced
+			cfg.AntiRinging = 1
+			cfg.MaxFilterRatio = 2
+			cfg.Sharpen = Sharpen{Amount: 0.5, Radius: 1}
+			converter, err := NewConverter(cfg, NewLanczosFilter(3))
+			expect(t, err, nil)
+			err = converter.Convert(ref, img)
+			expect(t, err, nil)
+			// scaling rows in chunks must not change output
+			err = converter.(ContextConverter).ConvertContext(c, dst, img)
+			expect(t, err, nil)
+			checkPsnrs(t, ref, dst, image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+		}
+	}
+	cancel()
+	dst := image.NewGray(image.Rect(0, 0, 100, 300))
+	converter, err := NewConverter(&ConverterConfig{
+		Input:  Descriptor{Width: 320, Height: 180, Ratio: Ratio444, Pack: 1, Planes: 1},
+		Output: Descriptor{Width: 100, Height: 300, Ratio: Ratio444, Pack: 1, Planes: 1},
+	}, NewBicubicFilter())
+	expect(t, err, nil)
+	err = converter.(ContextConverter).ConvertContext(c, dst, image.NewGray(image.Rect(0, 0, 320, 180)))
+	expect(t, err, gocontext.Canceled)
+	// cancel a long conversion while it runs
+	big := image.NewGray(image.Rect(0, 0, 4096, 4096))
+	out := image.NewGray(image.Rect(0, 0, 4000, 4000))
+	cfg, err := PrepareConversion(out, big)
+	expect(t, err, nil)
+	converter, err = NewConverter(cfg, NewLanczosFilter(3))
+	expect(t, err, nil)
+	// default pool workers are never released
+	getPool(nil)
+	goroutines := runtime.NumGoroutine()
+	c, cancel = gocontext.WithCancel(gocontext.Background())
+	time.AfterFunc(time.Millisecond, cancel)
+	err = converter.(ContextConverter).ConvertContext(c, out, big)
+	expect(t, err, gocontext.Canceled)
+	// only wait for the timer goroutine calling cancel
+	for i := 0; i < 100 && runtime.NumGoroutine() > goroutines; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	expect(t, runtime.NumGoroutine() <= goroutines, true)
+}
+
+func TestColumnSlices(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	sizes := []image.Point{{2000, 4}, {1024, 8}, {160, 4}, {37, 4}}
+	for _, img := range []image.Image{src, toRgb(src)} {
+		for _, size := range sizes {
+			for _, threads := range []int{2, 7, 32} {
+				for _, asm := range []bool{false, true} {
+					ref, err := newImageLike(img, size.X, size.Y)
+					expect(t, err, nil)
+					out, err := newImageLike(img, size.X, size.Y)
+					expect(t, err, nil)
+					cfg, err := PrepareConversion(ref, img)
+					expect(t, err, nil)
+					cfg.DisableAsm = !asm
+					cfg.AntiRinging = 0.5
+					cfg.Threads = 1
+					converter, err := NewConverter(cfg, NewLanczosFilter(3))
+					expect(t, err, nil)
+					err = converter.Convert(ref, img)
+					expect(t, err, nil)
+					cfg.Threads = threads
+					converter, err = NewConverter(cfg, NewLanczosFilter(3))
+					expect(t, err, nil)
+					err = converter.Convert(out, img)
+					expect(t, err, nil)
+					checkPsnrs(t, ref, out, image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+				}
+			}
+		}
+	}
+}
+
+func TestGetTiles(t *testing.T) {
+	tests := []struct {
+		threads, width, height int
+		columns                bool
+		rows, cols             int
+	}{
+		{1, 2000, 4, true, 1, 1},
+		{8, 2000, 4, true, 4, 2},
+		{32, 2000, 2, true, 2, 16},
+		{32, 2000, 2, false, 2, 1},
+		{32, 40, 1, true, 1, 2},
+		{4, 2000, 100, true, 4, 1},
+	}
+	for _, tt := range tests {
+		rows, cols := getTiles(tt.threads, tt.width, tt.height, tt.columns)
+		expect(t, rows, tt.rows)
+		expect(t, cols, tt.cols)
+	}
+}
+
+func TestConvertAllocs(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	gray := image.NewGray(src.Rect)
+	copy(gray.Pix, src.Y)
+	options := []func(cfg *ConverterConfig){
+		func(cfg *ConverterConfig) {},
+		func(cfg *ConverterConfig) {
+			cfg.AntiRinging = 0.5
+			cfg.Sharpen = Sharpen{Amount: 0.5, Radius: 1}
+		},
+		func(cfg *ConverterConfig) { cfg.StripRows = 8 },
+		func(cfg *ConverterConfig) { cfg.MaxFilterRatio = 1.5 },
+	}
+	pool := NewPool(0)
+	defer pool.Close()
+	for _, img := range []image.Image{src, toRgb(src), gray} {
+		for _, option := range options {
+			for _, asm := range []bool{false, true} {
+				for _, threads := range []int{1, 0, 4} {
+					for _, shared := range []*Pool{nil, pool} {
+						dst, err := newImageLike(img, 200, 100)
+						expect(t, err, nil)
+						cfg, err := PrepareConversion(dst, img)
+						expect(t, err, nil)
+						cfg.DisableAsm = !asm
+						cfg.Threads = threads
+						cfg.Pool = shared
+						option(cfg)
+						converter, err := NewConverter(cfg, NewBicubicFilter())
+						expect(t, err, nil)
+						allocs := testing.AllocsPerRun(10, func() {
+							converter.Convert(dst, img)
+						})
+						expect(t, allocs, 0.0)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAlignedImages(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	r := image.Rect(0, 0, 203, 101)
+	ratios := []image.YCbCrSubsampleRatio{
+		image.YCbCrSubsampleRatio411,
+		image.YCbCrSubsampleRatio420,
+		image.YCbCrSubsampleRatio422,
+		image.YCbCrSubsampleRatio440,
+		image.YCbCrSubsampleRatio444,
+	}
+	for _, ratio := range ratios {
+		aligned := NewYCbCr(r, ratio)
+		expect(t, aligned.YStride%imageAlign, 0)
+		expect(t, aligned.CStride%imageAlign, 0)
+		ref := image.NewYCbCr(r, ratio)
+		err = Convert(ref, src, NewBicubicFilter())
+		expect(t, err, nil)
+		err = Convert(aligned, src, NewBicubicFilter())
+		expect(t, err, nil)
+		checkPsnrs(t, ref, aligned, image.Rectangle{}, []float64{math.Inf(1), math.Inf(1), math.Inf(1)})
+	}
+	rgb := toRgb(src)
+	aligned := NewRGBA(r)
+	expect(t, aligned.Stride%imageAlign, 0)
+	ref := image.NewRGBA(r)
+	expect(t, Convert(ref, rgb, NewBicubicFilter()), nil)
+	expect(t, Convert(aligned, rgb, NewBicubicFilter()), nil)
+	checkPsnrs(t, ref, aligned, image.Rectangle{}, []float64{math.Inf(1)})
+	expect(t, NewNRGBA(r).Stride%imageAlign, 0)
+	expect(t, NewGray(r).Stride%imageAlign, 0)
+	// 4:1:0 has no matching descriptor
+	ycc := image.NewYCbCr(r, image.YCbCrSubsampleRatio410)
+	if Convert(ycc, src, NewBicubicFilter()) == nil {
+		t.Fatal("unsupported ratio must fail")
+	}
+}
+
+func TestImagePool(t *testing.T) {
+	pool := NewImagePool()
+	descs := []Descriptor{
+		{Width: 203, Height: 101, Ratio: Ratio420, Pack: 1, Planes: 3},
+		{Width: 203, Height: 102, Ratio: Ratio420, Pack: 1, Planes: 3, Interlaced: true},
+		{Width: 203, Height: 101, Ratio: Ratio411, Pack: 1, Planes: 3},
+		{Width: 203, Height: 101, Ratio: Ratio444, Pack: 4, Planes: 1},
+		{Width: 203, Height: 101, Ratio: Ratio444, Pack: 1, Planes: 1},
+	}
+	for _, d := range descs {
+		img, err := pool.Get(&d)
+		expect(t, err, nil)
+		id, _, err := inspect(img, d.Interlaced)
+		expect(t, err, nil)
+		expect(t, *id, d)
+		pool.Put(&d, img)
+		again, err := pool.Get(&d)
+		expect(t, err, nil)
+		expect(t, again, img)
+		other, err := pool.Get(&d)
+		expect(t, err, nil)
+		if other == img {
+			t.Fatal("pool returned an image twice")
+		}
+		src, err := NewImage(&Descriptor{Width: 64, Height: 32, Ratio: d.Ratio, Pack: d.Pack, Planes: d.Planes})
+		expect(t, err, nil)
+		cfg, err := PrepareConversion(img, src)
+		expect(t, err, nil)
+		cfg.Input.Interlaced = d.Interlaced
+		cfg.Output.Interlaced = d.Interlaced
+		converter, err := NewConverter(cfg, NewBicubicFilter())
+		expect(t, err, nil)
+		expect(t, converter.Convert(img, src), nil)
+	}
+	_, err := pool.Get(&Descriptor{Width: 16, Height: 16, Pack: 2, Planes: 1})
+	if err == nil {
+		t.Fatal("invalid descriptor must fail")
+	}
+}
+
+// randomPixels returns n random bytes biased toward 0 & 255, which stress
+// clamping & overflows
+func randomPixels(rnd *rand.Rand, n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		switch rnd.Intn(3) {
+		case 0:
+			data[i] = 0
+		case 1:
+			data[i] = 0xFF
+		default:
+			data[i] = byte(rnd.Intn(256))
+		}
+	}
+	return data
+}
+
+func TestScalerParity(t *testing.T) {
+	if !hasAsm() {
+		return
+	}
+	rnd := rand.New(rand.NewSource(0))
+	taps := []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 24, 64, 1024}
+	// coefficient ranges, the last one overflows int32 sums on big taps
+	ranges := []struct{ lo, hi int }{
+		{-1 << Bits, 1 << Bits},
+		{-1<<15 + 1, 1<<15 - 1},
+		{1 << Bits, 1<<15 - 1},
+	}
+	widths := []int{1, 2, 7, 15, 16, 17, 31, 37, 69}
+	height := 7
+	for _, n := range taps {
+		for _, r := range ranges {
+			for _, width := range widths {
+				for _, vertical := range []bool{false, true} {
+					size := width
+					if vertical {
+						size = height
+					}
+					raw := make([]int16, size*n)
+					for i := range raw {
+						raw[i] = int16(r.lo + rnd.Intn(r.hi-r.lo+1))
+					}
+					off := make([]int16, size)
+					sp := width + n + 16
+					rows := height + n
+					for i := range off {
+						if vertical {
+							off[i] = int16(rnd.Intn(2) * int(bin(i > 0)))
+						} else {
+							off[i] = int16(rnd.Intn(sp - n))
+						}
+					}
+					src := randomPixels(rnd, sp*rows)
+					dp := width + 16
+					ref := make([]byte, dp*height)
+					out := make([]byte, dp*height)
+					if vertical {
+						cof, _ := prepareVerticalCoeffs(raw, size, n)
+						getVerticalScaler(n, false)(ref, src, raw, off, n, width, height, dp, sp)
+						getVerticalScaler(n, true)(out, src, cof, off, n, width, height, dp, sp)
+					} else {
+						cof := prepareHorizontalCoeffs(raw, size, n)
+						getHorizontalScaler(n, false)(ref, src, raw, off, n, width, height, dp, sp)
+						getHorizontalScaler(n, true)(out, src, cof, padOffsets(off), n, width, height, dp, sp)
+					}
+					err := comparePlanes(0,
+						&Plane{Width: width, Height: height, Pitch: dp, Pack: 1, Data: out},
+						&Plane{Width: width, Height: height, Pitch: dp, Pack: 1, Data: ref})
+					if err != nil {
+						t.Fatalf("taps %v range %v width %v vertical %v: %v", n, r, width, vertical, err)
+					}
+					// anti-ringing scalers clamp pixels while resizing
+					strength := rnd.Intn(1<<Bits + 1)
+					if vertical {
+						cof, _ := prepareVerticalCoeffs(raw, size, n)
+						getVerticalRingScaler(false)(ref, src, raw, off, n, width, height, dp, sp, strength)
+						getVerticalRingScaler(true)(out, src, cof, off, n, width, height, dp, sp, strength)
+					} else {
+						cof := interleaveCoeffs(raw, size, n)
+						getHorizontalRingScaler(false)(ref, src, raw, off, n, width, height, dp, sp, strength)
+						getHorizontalRingScaler(true)(out, src, cof, padOffsets(off), n, width, height, dp, sp, strength)
+					}
+					err = comparePlanes(0,
+						&Plane{Width: width, Height: height, Pitch: dp, Pack: 1, Data: out},
+						&Plane{Width: width, Height: height, Pitch: dp, Pack: 1, Data: ref})
+					if err != nil {
+						t.Fatalf("ring taps %v range %v width %v vertical %v strength %v: %v",
+							n, r, width, vertical, strength, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestResizerParity(t *testing.T) {
+	if !hasAsm() {
+		return
+	}
+	rnd := rand.New(rand.NewSource(0))
+	filters := []Filter{
+		NewBilinearFilter(),
+		NewBicubicFilter(),
+		NewLanczosFilter(3),
+		NewLanczosFilter(4),
+		NewLanczosFilter(5),
+		NewLanczosFilter(6),
+		NewLanczosFilter(8),
+		NewBoxFilter(),
+		NewSpline64Filter(),
+	}
+	sizes := []struct{ in, out int }{
+		{64, 32}, {64, 16}, {32, 64}, {100, 37}, {37, 100}, {256, 40}, {33, 99},
+	}
+	for _, f := range filters {
+		for _, s := range sizes {
+			for pack := 1; pack <= 4; pack++ {
+				for mode := 0; mode < 6; mode++ {
+					// narrow planes run asm scalers on less than 16 bytes
+					vertical, interlaced, other := mode%3 > 0, mode%3 > 1, 24
+					if mode > 2 {
+						other = 3
+					}
+					var rez [2]Resizer
+					for i, asm := range []bool{false, true} {
+						rez[i] = NewResize(&ResizerConfig{
+							Depth:      8,
+							Input:      s.in,
+							Output:     s.out,
+							Vertical:   vertical,
+							Interlaced: interlaced,
+							Pack:       pack,
+							Threads:    1,
+							DisableAsm: !asm,
+						}, f)
+					}
+					width, height := s.in, other
+					dw, dh := s.out, height
+					if vertical {
+						width, height, dw, dh = other, s.in, other, s.out
+					}
+					sp := width*pack + 16
+					src := randomPixels(rnd, sp*height)
+					dp := dw*pack + 16
+					var out [2]*Plane
+					for i, r := range rez {
+						out[i] = &Plane{Width: dw, Height: dh, Pitch: dp, Pack: pack, Data: make([]byte, dp*dh)}
+						r.Resize(out[i].Data, src, width, height, dp, sp)
+					}
+					err := comparePlanes(0, out[1], out[0])
+					if err != nil {
+						t.Fatalf("%v %v->%v other %v pack %v vertical %v interlaced %v: %v",
+							f.Name(), s.in, s.out, other, pack, vertical, interlaced, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	raw := readImage(t, "testdata/lenna.jpg")
+	src := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+	err := Convert(src, raw, NewBicubicFilter())
+	expect(t, err, nil)
+	gray := image.NewGray(src.Rect)
+	copy(gray.Pix, src.Y)
+	for _, img := range []image.Image{src, toRgb(src), gray} {
+		for _, size := range []image.Point{{200, 100}, {640, 360}, {33, 17}} {
+			dst, err := newImageLike(img, size.X, size.Y)
+			expect(t, err, nil)
+			cfg, err := PrepareConversion(dst, img)
+			expect(t, err, nil)
+			cfg.Verify = true
+			cfg.AntiRinging = 0.5
+			cfg.Sharpen = Sharpen{Amount: 0.5, Radius: 1}
+			cfg.MaxFilterRatio = 1.5
+			converter, err := NewConverter(cfg, NewLanczosFilter(3))
+			expect(t, err, nil)
+			err = converter.Convert(dst, img)
+			expect(t, err, nil)
+		}
+	}
+	for _, interlaced := range []bool{false, true} {
+		cfg, err := PrepareConversion(src, src)
+		expect(t, err, nil)
+		cfg.Input.Interlaced = interlaced
+		cfg.Deinterlace = DeinterlaceBlend
+		cfg.Verify = true
+		outputs := []image.Image{}
+		descriptors := []Descriptor{}
+		for i, size := range []image.Point{{200, 100}, {640, 360}, {33, 18}} {
+			dst, err := newImageLike(src, size.X, size.Y)
+			expect(t, err, nil)
+			d, _, err := inspect(dst, interlaced && i == 0)
+			expect(t, err, nil)
+			outputs = append(outputs, dst)
+			descriptors = append(descriptors, *d)
+		}
+		multi, err := NewMultiConverter(cfg, descriptors, NewLanczosFilter(3))
+		expect(t, err, nil)
+		err = multi.Convert(outputs, src)
+		expect(t, err, nil)
+	}
+	a := &Plane{Width: 4, Height: 3, Pitch: 16, Pack: 4, Data: make([]byte, 48)}
+	b := &Plane{Width: 4, Height: 3, Pitch: 16, Pack: 4, Data: make([]byte, 48)}
+	expect(t, comparePlanes(1, a, b), nil)
+	a.Data[16*2+4*3+1] = 7
+	err = comparePlanes(1, a, b)
+	expect(t, err.Error(), "asm & go mismatch on plane 1 at pixel 3,2 channel 1: asm=7 go=0")
+}
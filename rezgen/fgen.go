@@ -0,0 +1,237 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	. "github.com/bamiaux/rez/asm"
+)
+
+// fast scalers resize rows by an integer ratio with constant coefficients
+// coefficients are pairs of taps broadcasted over a simd register, one
+// register per pair and per phase
+// packed scalers resize 4 bytes pixels, with the coefficients of one
+// component shared by every component
+type fast struct {
+	factor int // 2 or 4 for downscales, 0 for 2x upscales
+	pack   int // 1 or 4 bytes per pixel
+	// global data
+	hbits Operand
+	// arguments
+	dst    []Operand
+	src    []Operand
+	cof    []Operand
+	off    []Operand
+	taps   Operand
+	width  Operand
+	height Operand
+	dp     Operand
+	sp     Operand
+}
+
+func fgen(a *Asm) {
+	f := fast{}
+	f.hbits = a.Data("hbits", bytes.Repeat([]byte{0x00, 0x00, 0x20, 0x00}, 4))
+	f.genscale(a, "h8down2Amd64", 2, 1)
+	f.genscale(a, "h8down4Amd64", 4, 1)
+	f.genscale(a, "h8up2Amd64", 0, 1)
+	f.genscale(a, "h8down2Pack4Amd64", 2, 4)
+	f.genscale(a, "h8down4Pack4Amd64", 4, 4)
+	f.genscale(a, "h8up2Pack4Amd64", 0, 4)
+}
+
+func (f *fast) genscale(a *Asm, name string, factor, pack int) {
+	f.factor = factor
+	f.pack = pack
+	a.NewFunction(name)
+	// arguments
+	f.dst = a.SliceArgument("dst")
+	f.src = a.SliceArgument("src")
+	f.cof = a.SliceArgument("cof")
+	f.off = a.SliceArgument("off")
+	f.taps = a.Argument("taps")
+	f.width = a.Argument("width")
+	f.height = a.Argument("height")
+	f.dp = a.Argument("dp")
+	f.sp = a.Argument("sp")
+	a.Start()
+	f.frame(a)
+	a.Ret()
+}
+
+// registers:
+// SI & DI = src & dst, R9 & R10 = src & dst line references
+// R13 = cof, BX = coefficient pairs, R12 = bytes per phase
+// CX = simd loops per line, R8 = simd loops left
+// AX, DX & R11 = src, pairs left & cof in pair loop
+func (f *fast) frame(a *Asm) {
+	end := a.NewLabel("end")
+	a.Movq(CX, f.width)
+	a.Shrq(CX, Constant(xshift))
+	a.Orq(CX, CX)
+	a.Je(end)
+	a.Movq(BX, f.taps)
+	a.Shrq(BX, Constant(1))
+	a.Movq(R12, BX)
+	a.Shlq(R12, Constant(xshift))
+	a.Movq(R9, f.src[0])
+	a.Movq(R10, f.dst[0])
+	a.Movq(R13, f.cof[0])
+	a.Pxor(X15, X15)
+	a.Movo(X14, f.hbits)
+	yloop := a.NewLabel("yloop")
+	a.Label(yloop)
+	a.Movq(SI, R9)
+	a.Movq(DI, R10)
+	a.Movq(R8, CX)
+	xloop := a.NewLabel("xloop")
+	a.Label(xloop)
+	f.line(a)
+	a.Subq(R8, Constant(1))
+	a.Jne(xloop)
+	a.Addq(R9, f.sp)
+	a.Addq(R10, f.dp)
+	a.Subq(f.height, Constant(1))
+	a.Jne(yloop)
+	a.Label(end)
+}
+
+func (f *fast) line(a *Asm) {
+	a.Pxor(X0, X0)
+	a.Pxor(X1, X1)
+	a.Pxor(X2, X2)
+	a.Pxor(X3, X3)
+	a.Movq(DX, BX)
+	a.Movq(AX, SI)
+	a.Movq(R11, R13)
+	pairs := a.NewLabel("pairs")
+	a.Label(pairs)
+	a.Movou(X8, Address(R11))
+	if f.factor == 0 {
+		a.Movou(X9, Address(R11, R12))
+	}
+	switch {
+	case f.pack == 4:
+		f.packed(a)
+	case f.factor == 2:
+		f.down2(a)
+	case f.factor == 4:
+		f.down4(a)
+	default:
+		f.up2(a)
+	}
+	a.Addq(AX, Constant(2*f.pack))
+	a.Addq(R11, Constant(xwidth))
+	a.Subq(DX, Constant(1))
+	a.Jne(pairs)
+	if f.factor == 0 && f.pack == 1 {
+		// interleave both phases
+		a.Movo(X4, X0)
+		a.Movo(X6, X2)
+		a.Punpckldq(X0, X1)
+		a.Punpckhdq(X4, X1)
+		a.Punpckldq(X2, X3)
+		a.Punpckhdq(X6, X3)
+		f.flush(a, X0, X4, X2, X6)
+	} else {
+		f.flush(a, X0, X1, X2, X3)
+	}
+	if f.factor == 0 {
+		a.Addq(SI, Constant(xwidth>>1))
+	} else {
+		a.Addq(SI, Constant(xwidth*f.factor))
+	}
+	a.Addq(DI, Constant(xwidth))
+}
+
+// down2 accumulates one pair of taps for 16 pixels
+// each dword lane x holds src[2x+0] * cof[0] + src[2x+1] * cof[1]
+func (f *fast) down2(a *Asm) {
+	for i, x := range []SimdRegister{X0, X1, X2, X3} {
+		a.Movq(X4, Address(AX, i*8))
+		a.Punpcklbw(X4, X15)
+		a.Pmaddwd(X4, X8)
+		a.Paddd(x, X4)
+	}
+}
+
+// down4 accumulates one pair of taps for 16 pixels
+// pairs for src[4x+0] and src[4x+1] are on even dwords of the first load
+// and on odd dwords of the second load
+func (f *fast) down4(a *Asm) {
+	for i, x := range []SimdRegister{X0, X1, X2, X3} {
+		a.Movq(X4, Address(AX, i*16))
+		a.Movq(X5, Address(AX, i*16+6))
+		a.Punpcklbw(X4, X15)
+		a.Punpcklbw(X5, X15)
+		a.Pmaddwd(X4, X8)
+		a.Pmaddwd(X5, X8)
+		a.Shufps(X4, X5, Constant(0xD8))
+		a.Paddd(x, X4)
+	}
+}
+
+// up2 accumulates one pair of taps for 16 pixels
+// src[x+0] & src[x+1] are interleaved then multiplied by both phases
+func (f *fast) up2(a *Asm) {
+	acc := [][2]SimdRegister{{X0, X1}, {X2, X3}}
+	for i, x := range acc {
+		a.Movd(X4, Address(AX, i*4))
+		a.Movd(X5, Address(AX, i*4+1))
+		a.Punpcklbw(X4, X15)
+		a.Punpcklbw(X5, X15)
+		a.Punpcklwd(X4, X5)
+		a.Movo(X6, X4)
+		a.Pmaddwd(X4, X8)
+		a.Pmaddwd(X6, X9)
+		a.Paddd(x[0], X4)
+		a.Paddd(x[1], X6)
+	}
+}
+
+// packed accumulates one pair of taps for 4 pixels of 4 components
+// both pixels of a pair are interleaved by component, each dword lane c of
+// a register holds src[x+0][c] * cof[0] + src[x+1][c] * cof[1]
+// upscales read one input pixel for both phases of 2 output pixels
+func (f *fast) packed(a *Asm) {
+	acc := []SimdRegister{X0, X1, X2, X3}
+	if f.factor == 0 {
+		for i := 0; i < 2; i++ {
+			a.Movd(X4, Address(AX, i*4))
+			a.Movd(X5, Address(AX, i*4+4))
+			a.Punpcklbw(X4, X5)
+			a.Punpcklbw(X4, X15)
+			a.Movo(X6, X4)
+			a.Pmaddwd(X4, X8)
+			a.Pmaddwd(X6, X9)
+			a.Paddd(acc[i*2], X4)
+			a.Paddd(acc[i*2+1], X6)
+		}
+		return
+	}
+	for i, x := range acc {
+		a.Movd(X4, Address(AX, i*4*f.factor))
+		a.Movd(X5, Address(AX, i*4*f.factor+4))
+		a.Punpcklbw(X4, X5)
+		a.Punpcklbw(X4, X15)
+		a.Pmaddwd(X4, X8)
+		a.Paddd(x, X4)
+	}
+}
+
+func (f *fast) flush(a *Asm, xa, xb, xc, xd SimdRegister) {
+	a.Paddd(xa, X14)
+	a.Paddd(xb, X14)
+	a.Paddd(xc, X14)
+	a.Paddd(xd, X14)
+	a.Psrad(xa, Constant(14))
+	a.Psrad(xb, Constant(14))
+	a.Psrad(xc, Constant(14))
+	a.Psrad(xd, Constant(14))
+	a.Packssdw(xa, xb)
+	a.Packssdw(xc, xd)
+	a.Packuswb(xa, xc)
+	a.Movou(Address(DI), xa)
+}
@@ -32,6 +32,8 @@ func main() {
 		hgen(a)
 	case "vertical":
 		vgen(a)
+	case "fast":
+		fgen(a)
 	}
 	err := a.Flush()
 	if err != nil {
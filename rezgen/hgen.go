@@ -17,52 +17,59 @@ const (
 )
 
 type horizontal struct {
-	xtaps int
+	xtaps   int
+	partial bool // whether blocks store only the row tail
+	ring    bool // whether to clamp ringing
+	r       ring
 	// global data
-	zero  Operand
 	hbits Operand
-	u8max Operand
 	// arguments
-	dst    []Operand
-	src    []Operand
-	cof    []Operand
-	off    []Operand
-	taps   Operand
-	width  Operand
-	height Operand
-	dp     Operand
-	sp     Operand
+	dst      []Operand
+	src      []Operand
+	cof      []Operand
+	off      []Operand
+	taps     Operand
+	width    Operand
+	height   Operand
+	dp       Operand
+	sp       Operand
+	strength Operand
 	// stack
 	simdroll Operand
-	asmroll  Operand
+	tail     Operand
 	srcref   Operand
 	dstoff   Operand
-	sum      Operand
 	dstref   Operand
-	count    Operand
 	inner    Operand
 }
 
 func hgen(a *Asm) {
 	h := horizontal{}
-	h.zero = a.Data("zero", bytes.Repeat([]byte{0x00}, 16))
 	h.hbits = a.Data("hbits", bytes.Repeat([]byte{0x00, 0x00, 0x20, 0x00}, 4))
-	h.u8max = a.Data("u8max", bytes.Repeat([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF}, 2))
 	h.genscale(a, 2)
 	h.genscale(a, 4)
 	h.genscale(a, 8)
 	h.genscale(a, 10)
 	h.genscale(a, 12)
 	h.genscale(a, 0)
+	h.r = newRing(a)
+	h.gen(a, "h8ringNAmd64", 0, true)
 }
 
 func (h *horizontal) genscale(a *Asm, taps int) {
-	h.xtaps = taps
 	suffix := "N"
 	if taps > 0 {
 		suffix = fmt.Sprintf("%v", taps)
 	}
-	a.NewFunction("h8scale" + suffix + "Amd64")
+	h.gen(a, "h8scale"+suffix+"Amd64", taps, false)
+}
+
+// gen generates a scaler named name
+// ring scalers take a strength argument & only support interleaved taps
+func (h *horizontal) gen(a *Asm, name string, taps int, ring bool) {
+	h.xtaps = taps
+	h.ring = ring
+	a.NewFunction(name)
 	// arguments
 	h.dst = a.SliceArgument("dst")
 	h.src = a.SliceArgument("src")
@@ -73,15 +80,17 @@ func (h *horizontal) genscale(a *Asm, taps int) {
 	h.height = a.Argument("height")
 	h.dp = a.Argument("dp")
 	h.sp = a.Argument("sp")
+	if h.ring {
+		h.strength = a.Argument("strength")
+		h.r.pushStrength(a)
+	}
 	// stack
 	h.simdroll = a.PushStack("simdroll")
-	h.asmroll = a.PushStack("asmroll")
+	h.tail = a.PushStack("tail")
 	h.srcref = a.PushStack("srcref")
 	h.dstoff = a.PushStack("dstoff")
-	h.sum = a.PushStack("sum")
 	if h.xtaps == 0 {
 		h.dstref = a.PushStack("dstref")
-		h.count = a.PushStack("count")
 		h.inner = a.PushStack("inner")
 	}
 	a.Start()
@@ -90,6 +99,9 @@ func (h *horizontal) genscale(a *Asm, taps int) {
 }
 
 func (h *horizontal) setup(a *Asm) {
+	if h.ring {
+		h.r.setup(a, h.strength)
+	}
 	a.Movq(BX, h.dp)
 	a.Movq(CX, h.width)
 	a.Movq(DX, CX)
@@ -98,14 +110,7 @@ func (h *horizontal) setup(a *Asm) {
 	a.Andq(DX, Constant(1<<xshift-1))
 	a.Movq(h.dstoff, BX)
 	a.Movq(h.simdroll, CX)
-	a.Movq(h.asmroll, DX)
-	if false {
-		// disable simd loops
-		a.Movq(AX, Constant(0))
-		a.Movq(h.simdroll, AX)
-		a.Movq(AX, h.width)
-		a.Movq(h.asmroll, AX)
-	}
+	a.Movq(h.tail, DX)
 	a.Movq(AX, h.src[0])
 	a.Movq(h.srcref, AX)
 	a.Movq(DX, h.taps)
@@ -140,7 +145,6 @@ func (h *horizontal) nextline(a *Asm) {
 
 func (h *horizontal) line(a *Asm) {
 	simdloop := a.NewLabel("simdloop")
-	asmloop := a.NewLabel("asmloop")
 	nosimdloop := a.NewLabel("nosimdloop")
 	end := a.NewLabel("end")
 
@@ -151,76 +155,36 @@ func (h *horizontal) line(a *Asm) {
 
 	// apply simd loops
 	a.Label(simdloop)
-	switch h.xtaps {
-	case 2:
-		h.taps2(a)
-	case 4:
-		h.taps4(a)
-	case 8:
-		h.taps8(a)
-	case 10, 12, 0:
-		h.tapsn(a)
-	}
+	h.block(a, false)
 	a.Subq(CX, Constant(1))
 	a.Jne(simdloop)
 
-	// check if we have asm loops
+	// check if we have a tail
 	a.Label(nosimdloop)
-	a.Movq(CX, h.asmroll)
+	a.Movq(CX, h.tail)
 	a.Orq(CX, CX)
 	a.Je(end)
 
-	// apply asm loops
-	a.Label(asmloop)
-	h.asmtaps(a)
-	a.Subq(CX, Constant(1))
-	a.Jne(asmloop)
+	// apply one more simd loop on padded tables, storing only the tail
+	h.block(a, true)
 
 	a.Label(end)
 }
 
-func (h *horizontal) taps1(a *Asm, idx int) {
-	a.Movwqsx(DX, Address(BX))
-	a.Movbqzx(AX, Address(SI, DX, idx))
-	a.Movwqsx(DX, Address(BP, idx*2))
-	a.Imulq(DX)
-}
-
-func (h *horizontal) asmtaps(a *Asm) {
-	h.taps1(a, 0)
-	a.Movq(h.sum, AX)
-	if h.xtaps > 0 {
-		i := 1
-		for ; i <= h.xtaps-2; i++ {
-			h.taps1(a, i)
-			a.Addq(h.sum, AX)
-		}
-		h.taps1(a, i)
-		a.Addq(BP, Constant(h.xtaps*2))
-	} else {
-		a.Movq(AX, h.inner)
-		a.Movq(h.count, AX)
-		loop := a.NewLabel("loop")
-		a.Label(loop)
-		h.taps1(a, 1)
-		a.Addq(SI, Constant(1))
-		a.Addq(BP, Constant(2))
-		a.Addq(h.sum, AX)
-		a.Subq(h.count, Constant(1))
-		a.Jne(loop)
-		h.taps1(a, 1)
-		a.Addq(BP, Constant(2*2))
-		a.Subq(SI, h.inner)
+// block resizes 16 pixels
+// partial = whether to store only the row tail
+func (h *horizontal) block(a *Asm, partial bool) {
+	h.partial = partial
+	switch h.xtaps {
+	case 2:
+		h.taps2(a)
+	case 4:
+		h.taps4(a)
+	case 8:
+		h.taps8(a)
+	case 10, 12, 0:
+		h.tapsn(a)
 	}
-	a.Addq(AX, h.sum)
-	a.Addq(AX, Constant(1<<(14-1)))
-	a.Cmovql(AX, h.zero)
-	a.Shrq(AX, Constant(14))
-	a.Cmpq(AX, h.u8max)
-	a.Cmovql(AX, h.u8max)
-	a.Addq(BX, Constant(xoffset))
-	a.Movb(Address(DI), AL)
-	a.Addq(DI, Constant(1))
 }
 
 func (h *horizontal) load2(a *Asm, op Operand, idx uint) {
@@ -268,6 +232,15 @@ func (h *horizontal) flush(a *Asm, xa, xb, xc, xd SimdRegister, op Register, cou
 	a.Packssdw(xa, xb)
 	a.Packssdw(xc, xd)
 	a.Packuswb(xa, xc)
+	if h.ring {
+		reduce(a, X8, X10, X12, X13, a.Pminub)
+		reduce(a, X9, X11, X12, X13, a.Pmaxub)
+		h.r.dering(a, xa, X8, X9, X7, xb, xc, xd, X4, X15, X14)
+	}
+	if h.partial {
+		storepart(a, xa, DI, h.tail, AX)
+		return
+	}
 	a.Movou(Address(DI), xa)
 	a.Addq(DI, Constant(xwidth))
 }
@@ -361,11 +334,22 @@ func (h *horizontal) loadn(a *Asm, xa, xb, xc, xd SimdRegister) {
 }
 
 func (h *horizontal) maddn(a *Asm, xa, xb, xc, xd SimdRegister) {
+	if h.ring {
+		h.ringpair(a, xa, xb, X8, X9, 0)
+		h.ringpair(a, xc, xd, X10, X11, 2)
+	}
 	h.madd(a, xa, xb, xc, xd, 0)
 	a.Addq(BP, Constant(xwidth*4))
 }
 
 func (h *horizontal) tapsn(a *Asm) {
+	if h.ring {
+		// X8 & X10 = lo, X9 & X11 = hi
+		a.Pcmpeqb(X8, X8)
+		a.Pxor(X9, X9)
+		a.Pcmpeqb(X10, X10)
+		a.Pxor(X11, X11)
+	}
 	h.loadn(a, X0, X1, X2, X3)
 	h.maddn(a, X0, X1, X2, X3)
 	// unloop when we know how many taps
@@ -380,6 +364,9 @@ func (h *horizontal) tapsn(a *Asm) {
 	if h.xtaps == 0 {
 		a.Movq(h.dstref, DI)
 		a.Movq(DI, h.inner)
+		noloop := a.NewLabel("noloop")
+		a.Orq(DI, DI)
+		a.Je(noloop)
 		loop := a.NewLabel("loop")
 		a.Label(loop)
 		h.loadn(a, X4, X5, X6, X7)
@@ -390,9 +377,40 @@ func (h *horizontal) tapsn(a *Asm) {
 		a.Paddd(X3, X7)
 		a.Subq(DI, Constant(2))
 		a.Jne(loop)
+		a.Label(noloop)
 		a.Movq(DI, h.dstref)
 	}
 	a.Movq(AX, h.taps)
 	a.Subq(SI, AX)
 	h.flush(a, X0, X1, X2, X3, BX, xoffset)
 }
+
+// ringpair updates lo & hi with significant taps of 8 pixels loaded in xa &
+// xb, two taps per pixel, cof = index of xa coefficients
+func (h *horizontal) ringpair(a *Asm, xa, xb, lo, hi SimdRegister, cof int) {
+	a.Movo(X12, Address(BP, cof*xwidth))
+	a.Pcmpgtw(X12, h.r.thr)
+	a.Movo(X13, Address(BP, (cof+1)*xwidth))
+	a.Pcmpgtw(X13, h.r.thr)
+	a.Packsswb(X12, X13)
+	a.Movo(X13, xa)
+	a.Punpcklqdq(X13, xb)
+	a.Pand(X13, X12)
+	a.Pmaxub(hi, X13)
+	a.Pxor(X12, h.r.ones)
+	a.Por(X12, X13)
+	a.Pminub(lo, X12)
+}
+
+// reduce merges ranges of tap pairs into pixel ranges
+// la & lb hold 8 pixels of two taps each & la is set to their 16 pixels
+func reduce(a *Asm, la, lb, ta, tb SimdRegister, merge func(opa, opb Operand)) {
+	for _, r := range []struct{ l, t SimdRegister }{{la, ta}, {lb, tb}} {
+		a.Movo(r.t, r.l)
+		a.Psrlw(r.t, Constant(8))
+		a.Psllw(r.l, Constant(8))
+		a.Psrlw(r.l, Constant(8))
+		merge(r.l, r.t)
+	}
+	a.Packuswb(la, lb)
+}
@@ -0,0 +1,85 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	. "github.com/bamiaux/rez/asm"
+)
+
+// partial loads & stores let simd loops process the last pixels of a row,
+// when less than a simd register is left, without any access past the row
+// width is a multiple of 1, 2, 4 & 8 bytes pieces of at most 15 bytes
+
+// loadpart loads width bytes at base into the low bytes of x, & zeroes the
+// others, one piece at a time, from the last one to the first one
+func loadpart(a *Asm, x SimdRegister, base Register, width Operand, pos, tmp Register) {
+	a.Pxor(x, x)
+	a.Movq(pos, width)
+	skip1 := a.NewLabel("skip1")
+	a.Testq(width, Constant(1))
+	a.Je(skip1)
+	a.Subq(pos, Constant(1))
+	a.Movbqzx(tmp, Address(base, pos, SX1))
+	a.Movq(x, tmp)
+	a.Label(skip1)
+	skip2 := a.NewLabel("skip2")
+	a.Testq(width, Constant(2))
+	a.Je(skip2)
+	a.Subq(pos, Constant(2))
+	a.Pslldq(x, Constant(2))
+	a.Pinsrw(x, Address(base, pos, SX1), Constant(0))
+	a.Label(skip2)
+	skip4 := a.NewLabel("skip4")
+	a.Testq(width, Constant(4))
+	a.Je(skip4)
+	a.Subq(pos, Constant(4))
+	a.Pslldq(x, Constant(4))
+	a.Pinsrw(x, Address(base, pos, SX1), Constant(0))
+	a.Pinsrw(x, Address(base, pos, SX1, 2), Constant(1))
+	a.Label(skip4)
+	skip8 := a.NewLabel("skip8")
+	a.Testq(width, Constant(8))
+	a.Je(skip8)
+	a.Pslldq(x, Constant(8))
+	for i := 0; i < 4; i++ {
+		a.Pinsrw(x, Address(base, i*2), Constant(i))
+	}
+	a.Label(skip8)
+}
+
+// storepart stores the width low bytes of x at dst, one piece at a time,
+// & advances dst by width, x is destroyed
+func storepart(a *Asm, x SimdRegister, dst Register, width Operand, tmp Register) {
+	skip8 := a.NewLabel("skip8")
+	a.Testq(width, Constant(8))
+	a.Je(skip8)
+	a.Movq(Address(dst), x)
+	a.Psrldq(x, Constant(8))
+	a.Addq(dst, Constant(8))
+	a.Label(skip8)
+	skip4 := a.NewLabel("skip4")
+	a.Testq(width, Constant(4))
+	a.Je(skip4)
+	a.Movq(tmp, x)
+	a.Movd(Address(dst), tmp)
+	a.Psrldq(x, Constant(4))
+	a.Addq(dst, Constant(4))
+	a.Label(skip4)
+	skip2 := a.NewLabel("skip2")
+	a.Testq(width, Constant(2))
+	a.Je(skip2)
+	a.Movq(tmp, x)
+	a.Movw(Address(dst), tmp)
+	a.Psrldq(x, Constant(2))
+	a.Addq(dst, Constant(2))
+	a.Label(skip2)
+	skip1 := a.NewLabel("skip1")
+	a.Testq(width, Constant(1))
+	a.Je(skip1)
+	a.Movq(tmp, x)
+	a.Movb(Address(dst), tmp)
+	a.Addq(dst, Constant(1))
+	a.Label(skip1)
+}
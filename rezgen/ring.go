@@ -0,0 +1,92 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	. "github.com/bamiaux/rez/asm"
+)
+
+const (
+	// coefficients above ringThreshold are significant for anti-ringing
+	ringThreshold = 1 << (14 - 5)
+)
+
+// ring scalers limit every pixel to the range of its significant source
+// pixels while resizing, lo & hi registers hold the running range
+type ring struct {
+	thr        Operand // ringThreshold words
+	ones       Operand // all bits set
+	strength   Operand // strength words on stack, unaligned
+	strengthhi Operand // upper half of strength
+}
+
+func newRing(a *Asm) ring {
+	return ring{
+		thr:  a.Data("ringthr", bytes.Repeat([]byte{ringThreshold >> 8, ringThreshold & 0xFF}, 8)),
+		ones: a.Data("ones", bytes.Repeat([]byte{0xFF}, 16)),
+	}
+}
+
+// pushStrength reserves stack room for strength words
+func (r *ring) pushStrength(a *Asm) {
+	r.strengthhi = a.PushStack("strengthhi")
+	r.strength = a.PushStack("strength")
+}
+
+// setup broadcasts the strength argument over 8 words on stack
+func (r *ring) setup(a *Asm, strength Operand) {
+	a.Movq(AX, strength)
+	a.Movq(DX, Constant(uint64(0x0001000100010001)))
+	a.Imulq(DX)
+	a.Movq(r.strength, AX)
+	a.Movq(r.strengthhi, AX)
+}
+
+// dering pulls x pixels toward their [lo, hi] range by strength/(1<<14)
+// pixels without significant source pixels have lo > hi & are left as is
+// lo, hi & temporaries are destroyed
+func (r *ring) dering(a *Asm, x, lo, hi, s, t0, t1, t2, t3 SimdRegister, zero, hbits Operand) {
+	// t0 = lanes with lo <= hi
+	a.Movo(t0, lo)
+	a.Pminub(t0, hi)
+	a.Pcmpeqb(t0, lo)
+	a.Pmaxub(lo, x)
+	a.Pminub(lo, hi)
+	a.Pand(lo, t0)
+	a.Pandn(t0, x)
+	a.Por(lo, t0)
+	// x += ((lo - x) * strength + 1<<13) >> 14 on words
+	a.Movou(s, r.strength)
+	a.Movo(t0, x)
+	a.Punpcklbw(t0, zero)
+	a.Movo(t1, lo)
+	a.Punpcklbw(t1, zero)
+	r.mulhalf(a, t0, t1, s, t2, t3, hbits)
+	a.Movo(t0, x)
+	a.Punpckhbw(t0, zero)
+	a.Movo(t2, lo)
+	a.Punpckhbw(t2, zero)
+	r.mulhalf(a, t0, t2, s, t3, hi, hbits)
+	a.Packuswb(t1, t2)
+	a.Movo(x, t1)
+}
+
+// mulhalf sets c words to v + ((c - v) * s + 1<<13) >> 14
+func (r *ring) mulhalf(a *Asm, v, c, s, ta, tb SimdRegister, hbits Operand) {
+	a.Psubw(c, v)
+	a.Movo(ta, c)
+	a.Pmulhw(ta, s)
+	a.Pmullw(c, s)
+	a.Movo(tb, c)
+	a.Punpcklwd(c, ta)
+	a.Punpckhwd(tb, ta)
+	a.Paddd(c, hbits)
+	a.Paddd(tb, hbits)
+	a.Psrad(c, Constant(14))
+	a.Psrad(tb, Constant(14))
+	a.Packssdw(c, tb)
+	a.Paddw(c, v)
+}
@@ -12,19 +12,22 @@ import (
 
 type vertical struct {
 	xtaps int
+	ring  bool // whether to clamp ringing
+	r     ring
 	// global data
 	zero  Operand
 	hbits Operand
 	// arguments
-	dst    []Operand
-	src    []Operand
-	cof    []Operand
-	off    []Operand
-	taps   Operand
-	width  Operand
-	height Operand
-	dp     Operand
-	sp     Operand
+	dst      []Operand
+	src      []Operand
+	cof      []Operand
+	off      []Operand
+	taps     Operand
+	width    Operand
+	height   Operand
+	dp       Operand
+	sp       Operand
+	strength Operand
 	// stack
 	srcref   Operand
 	offref   Operand
@@ -32,6 +35,7 @@ type vertical struct {
 	maxroll  Operand
 	backroll Operand
 	inner    Operand
+	count    Operand
 }
 
 func vgen(a *Asm) {
@@ -45,15 +49,24 @@ func vgen(a *Asm) {
 	v.genscale(a, 10)
 	v.genscale(a, 12)
 	v.genscale(a, 0)
+	v.r = newRing(a)
+	v.gen(a, "v8ringNAmd64", 0, true)
 }
 
 func (v *vertical) genscale(a *Asm, taps int) {
-	v.xtaps = taps
 	suffix := "N"
 	if taps > 0 {
 		suffix = fmt.Sprintf("%v", taps)
 	}
-	a.NewFunction("v8scale" + suffix + "Amd64")
+	v.gen(a, "v8scale"+suffix+"Amd64", taps, false)
+}
+
+// gen generates a scaler named name
+// ring scalers take a strength argument
+func (v *vertical) gen(a *Asm, name string, taps int, ring bool) {
+	v.xtaps = taps
+	v.ring = ring
+	a.NewFunction(name)
 	// arguments
 	v.dst = a.SliceArgument("dst")
 	v.src = a.SliceArgument("src")
@@ -64,6 +77,10 @@ func (v *vertical) genscale(a *Asm, taps int) {
 	v.height = a.Argument("height")
 	v.dp = a.Argument("dp")
 	v.sp = a.Argument("sp")
+	if v.ring {
+		v.strength = a.Argument("strength")
+		v.r.pushStrength(a)
+	}
 	// stack
 	v.srcref = R9
 	v.offref = R10
@@ -73,6 +90,7 @@ func (v *vertical) genscale(a *Asm, taps int) {
 	if v.xtaps == 0 {
 		v.inner = R14
 	}
+	v.count = a.PushStack("count")
 	a.Start()
 	v.frame(a)
 	a.Ret()
@@ -100,6 +118,9 @@ func (v *vertical) frame(a *Asm) {
 }
 
 func (v *vertical) setup(a *Asm) {
+	if v.ring {
+		v.r.setup(a, v.strength)
+	}
 	a.Movq(BX, v.dp)
 	a.Movq(CX, v.width)
 	a.Movq(DX, CX)
@@ -145,24 +166,133 @@ func (v *vertical) line(a *Asm) {
 	if v.xtaps == 2 {
 		taps = v.taps2
 	}
+	if v.ring {
+		taps = func(a *Asm) { v.pairs(a, false) }
+	}
 	a.Movq(CX, v.maxroll)
 	a.Orq(CX, CX)
-	nomaxloop := a.NewLabel("nomaxloop")
-	a.Je(nomaxloop)
+	// rows smaller than a simd register cannot roll back
+	narrow := a.NewLabel("narrow")
+	a.Je(narrow)
 	maxloop := a.NewLabel("maxloop")
 	a.Label(maxloop)
 	taps(a)
 	a.Subq(CX, Constant(1))
 	a.Jne(maxloop)
-	a.Label(nomaxloop)
 	a.Movq(CX, v.backroll)
 	a.Subq(SI, v.backroll)
 	a.Subq(DI, v.backroll)
 	a.Orq(CX, CX)
-	nobackroll := a.NewLabel("nobackroll")
-	a.Je(nobackroll)
+	end := a.NewLabel("end")
+	a.Je(end)
 	taps(a)
-	a.Label(nobackroll)
+	a.Jmp(end)
+
+	// load & store only width bytes of every row
+	a.Label(narrow)
+	v.pairs(a, true)
+
+	a.Label(end)
+}
+
+// pairs resizes 16 pixels one pair of taps at a time
+// partial = whether to load & store only width bytes of every row, so that
+// rows smaller than a simd register never access bytes past their width
+// registers:
+// AX = src rows, R15 = coefficient pairs, R8 & DX = temporaries
+// X8 & X9 = lo & hi ranges of ring scalers
+func (v *vertical) pairs(a *Asm, partial bool) {
+	a.Pxor(X0, X0)
+	a.Pxor(X1, X1)
+	a.Pxor(X2, X2)
+	a.Pxor(X3, X3)
+	if v.ring {
+		a.Pcmpeqb(X8, X8)
+		a.Pxor(X9, X9)
+	}
+	a.Movq(AX, SI)
+	a.Movq(R15, BP)
+	if v.xtaps > 0 {
+		a.Movq(v.count, Constant(v.xtaps>>1))
+	} else {
+		a.Movq(DX, v.taps)
+		a.Shrq(DX, Constant(1))
+		a.Movq(v.count, DX)
+	}
+	loop := a.NewLabel("loop")
+	a.Label(loop)
+	if partial {
+		loadpart(a, X4, AX, v.width, R8, DX)
+		a.Addq(AX, BX)
+		loadpart(a, X7, AX, v.width, R8, DX)
+	} else {
+		a.Movou(X4, Address(AX))
+		a.Addq(AX, BX)
+		a.Movou(X7, Address(AX))
+	}
+	a.Addq(AX, BX)
+	if v.ring {
+		v.ringrow(a, X4, 0)
+		v.ringrow(a, X7, xoffset)
+	}
+	a.Movo(X6, X4)
+	a.Punpcklbw(X4, X7)
+	a.Punpckhbw(X6, X7)
+	a.Movo(X5, X4)
+	a.Movo(X7, X6)
+	a.Punpcklbw(X4, X14)
+	a.Punpckhbw(X5, X14)
+	a.Punpcklbw(X6, X14)
+	a.Punpckhbw(X7, X14)
+	a.Pmaddwd(X4, Address(R15))
+	a.Pmaddwd(X5, Address(R15))
+	a.Pmaddwd(X6, Address(R15))
+	a.Pmaddwd(X7, Address(R15))
+	a.Paddd(X0, X4)
+	a.Paddd(X1, X5)
+	a.Paddd(X2, X6)
+	a.Paddd(X3, X7)
+	a.Addq(R15, Constant(xwidth*2))
+	a.Subq(v.count, Constant(1))
+	a.Jne(loop)
+	v.pack(a)
+	if v.ring {
+		v.r.dering(a, X0, X8, X9, X5, X1, X2, X3, X4, X14, X13)
+	}
+	if partial {
+		storepart(a, X0, DI, v.width, DX)
+		return
+	}
+	a.Movou(Address(DI), X0)
+	a.Addq(SI, Constant(xwidth))
+	a.Addq(DI, Constant(xwidth))
+}
+
+// ringrow updates X8 & X9 ranges with row x if its coefficient at cof
+// offset is significant
+func (v *vertical) ringrow(a *Asm, x SimdRegister, cof int) {
+	skip := a.NewLabel("skip")
+	a.Movwqsx(DX, Address(R15, cof))
+	a.Cmpq(Constant(ringThreshold), DX)
+	a.Jle(skip)
+	a.Pminub(X8, x)
+	a.Pmaxub(X9, x)
+	a.Label(skip)
+}
+
+// pack rounds & packs X0, X1, X2 & X3 sums into X0 pixels
+func (v *vertical) pack(a *Asm) {
+	a.Paddd(X0, X13)
+	a.Paddd(X1, X13)
+	a.Paddd(X2, X13)
+	a.Paddd(X3, X13)
+	a.Psrad(X0, Constant(14))
+	a.Psrad(X1, Constant(14))
+	a.Psrad(X2, Constant(14))
+	a.Psrad(X3, Constant(14))
+	a.Packssdw(X0, X1)
+	a.Packssdw(X2, X3)
+	a.Packuswb(X0, X2)
 }
 
 func (v *vertical) taps2(a *Asm) {
@@ -182,17 +312,7 @@ func (v *vertical) taps2(a *Asm) {
 	a.Pmaddwd(X1, X12)
 	a.Pmaddwd(X2, X12)
 	a.Pmaddwd(X3, X12)
-	a.Paddd(X0, X13)
-	a.Paddd(X1, X13)
-	a.Paddd(X2, X13)
-	a.Paddd(X3, X13)
-	a.Psrad(X0, Constant(14))
-	a.Psrad(X1, Constant(14))
-	a.Psrad(X2, Constant(14))
-	a.Psrad(X3, Constant(14))
-	a.Packssdw(X0, X1)
-	a.Packssdw(X2, X3)
-	a.Packuswb(X0, X2)
+	v.pack(a)
 	a.Movou(Address(DI), X0)
 	a.Addq(SI, Constant(xwidth))
 	a.Addq(DI, Constant(xwidth))
@@ -208,17 +328,7 @@ func (v *vertical) tapsn(a *Asm) {
 	} else if v.xtaps != 4 {
 		v.left2taps(a)
 	}
-	a.Paddd(X0, X13)
-	a.Paddd(X1, X13)
-	a.Paddd(X2, X13)
-	a.Paddd(X3, X13)
-	a.Psrad(X0, Constant(14))
-	a.Psrad(X1, Constant(14))
-	a.Psrad(X2, Constant(14))
-	a.Psrad(X3, Constant(14))
-	a.Packssdw(X0, X1)
-	a.Packssdw(X2, X3)
-	a.Packuswb(X0, X2)
+	v.pack(a)
 	a.Movou(Address(DI), X0)
 	a.Addq(SI, Constant(xwidth))
 	a.Addq(DI, Constant(xwidth))
@@ -13,6 +13,9 @@ const (
 	Bits = 14
 )
 
+// u8 clamps x to a byte
+// Go scalers accumulate in int32 before calling u8, so that they wrap
+// exactly like simd scalers and both produce bit-identical outputs
 func u8(x int) byte {
 	if x < 0 {
 		x = 0
@@ -58,11 +61,11 @@ func h8scaleNGo(dst, src []byte, cof, off []int16,
 		s := src[si:]
 		d := dst[di:]
 		for x, xoff := range off[:width] {
-			pix := 0
+			pix := int32(0)
 			for i, v := range s[xoff : xoff+int16(taps)] {
-				pix += int(v) * int(c[i])
+				pix += int32(v) * int32(c[i])
 			}
-			d[x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 			c = c[taps:]
 		}
 		di += dp
@@ -76,13 +79,112 @@ func v8scaleNGo(dst, src []byte, cof, off []int16,
 	for _, yoff := range off[:height] {
 		src = src[sp*int(yoff):]
 		for x := range dst[di : di+width] {
-			pix := 0
+			pix := int32(0)
 			for i, c := range cof[:taps] {
-				pix += int(c) * int(src[sp*i+x])
+				pix += int32(c) * int32(src[sp*i+x])
 			}
-			dst[di+x] = u8((pix + 1<<(Bits-1)) >> Bits)
+			dst[di+x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
 		}
 		cof = cof[taps:]
 		di += dp
 	}
 }
+
+const (
+	// coefficients above ringThreshold are significant for anti-ringing
+	ringThreshold = 1 << (Bits - 5)
+)
+
+// deringPixel pulls pix toward the [lo, hi] range by strength/(1<<Bits)
+func deringPixel(pix, lo, hi byte, strength int) byte {
+	v := int(pix)
+	c := clip(v, int(lo), int(hi))
+	return byte(v + ((c-v)*strength+1<<(Bits-1))>>Bits)
+}
+
+// ringScaler is a scaler limiting ringing by strength/(1<<Bits)
+type ringScaler func(dst, src []byte, cof, off []int16,
+	taps, width, height, dstPitch, srcPitch, strength int)
+
+// getRingScaler returns a scaler which limits every dst pixel to the range
+// of its significant src taps, by strength in [0, 1]
+func getRingScaler(vertical bool, strength float64, asm bool) scaler {
+	s := clip(int(strength*(1<<Bits)+0.5), 0, 1<<Bits)
+	ring := getHorizontalRingScaler(asm)
+	if vertical {
+		ring = getVerticalRingScaler(asm)
+	}
+	return func(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int) {
+		ring(dst, src, cof, off, taps, width, height, dp, sp, s)
+	}
+}
+
+func h8ringGo(dst, src []byte, cof, off []int16,
+	taps, width, height, dp, sp, strength int) {
+	di := 0
+	si := 0
+	for y := 0; y < height; y++ {
+		c := cof
+		s := src[si:]
+		d := dst[di:]
+		for x, xoff := range off[:width] {
+			pix := int32(0)
+			lo, hi := byte(0xFF), byte(0)
+			for i, v := range s[xoff : xoff+int16(taps)] {
+				pix += int32(v) * int32(c[i])
+				if c[i] > ringThreshold {
+					lo = minu8(lo, v)
+					hi = maxu8(hi, v)
+				}
+			}
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
+			if lo <= hi {
+				d[x] = deringPixel(d[x], lo, hi, strength)
+			}
+			c = c[taps:]
+		}
+		di += dp
+		si += sp
+	}
+}
+
+func v8ringGo(dst, src []byte, cof, off []int16,
+	taps, width, height, dp, sp, strength int) {
+	di := 0
+	for _, yoff := range off[:height] {
+		src = src[sp*int(yoff):]
+		d := dst[di : di+width]
+		for x := range d {
+			pix := int32(0)
+			lo, hi := byte(0xFF), byte(0)
+			for i, c := range cof[:taps] {
+				v := src[sp*i+x]
+				pix += int32(c) * int32(v)
+				if c > ringThreshold {
+					lo = minu8(lo, v)
+					hi = maxu8(hi, v)
+				}
+			}
+			d[x] = u8(int((pix + 1<<(Bits-1)) >> Bits))
+			if lo <= hi {
+				d[x] = deringPixel(d[x], lo, hi, strength)
+			}
+		}
+		cof = cof[taps:]
+		di += dp
+	}
+}
+
+func minu8(a, b byte) byte {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxu8(a, b byte) byte {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -19,6 +19,14 @@ func v8scale8Amd64(dst, src []byte, cof, off []int16, taps, width, height, dp, s
 func v8scale10Amd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int)
 func v8scale12Amd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int)
 func v8scaleNAmd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int)
+func h8ringNAmd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp, strength int)
+func v8ringNAmd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp, strength int)
+func h8down2Amd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int)
+func h8down4Amd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int)
+func h8up2Amd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int)
+func h8down2Pack4Amd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int)
+func h8down4Pack4Amd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int)
+func h8up2Pack4Amd64(dst, src []byte, cof, off []int16, taps, width, height, dp, sp int)
 
 func getHorizontalScaler(taps int, asm bool) scaler {
 	if !asm {
@@ -59,3 +67,38 @@ func getVerticalScaler(taps int, asm bool) scaler {
 	}
 	return v8scaleNAmd64
 }
+
+func getHorizontalRingScaler(asm bool) ringScaler {
+	if !asm {
+		return h8ringGo
+	}
+	return h8ringNAmd64
+}
+
+func getVerticalRingScaler(asm bool) ringScaler {
+	if !asm {
+		return v8ringGo
+	}
+	return v8ringNAmd64
+}
+
+func getFastScaler(period, step, pack int, asm bool) scaler {
+	if !asm {
+		return nil
+	}
+	switch {
+	case pack == 1 && period == 1 && step == 2:
+		return h8down2Amd64
+	case pack == 1 && period == 1 && step == 4:
+		return h8down4Amd64
+	case pack == 1 && period == 2 && step == 1:
+		return h8up2Amd64
+	case pack == 4 && period == 1 && step == 2:
+		return h8down2Pack4Amd64
+	case pack == 4 && period == 1 && step == 4:
+		return h8down4Pack4Amd64
+	case pack == 4 && period == 2 && step == 1:
+		return h8up2Pack4Amd64
+	}
+	return nil
+}
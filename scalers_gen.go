@@ -2,6 +2,7 @@
 // Use of this source code is governed by a MIT-style
 // license that can be found in the LICENSE file.
 
+//go:build !amd64
 // +build !amd64
 
 package rez
@@ -15,3 +16,15 @@ func getHorizontalScaler(taps int, asm bool) scaler {
 func getVerticalScaler(taps int, asm bool) scaler {
 	return getVerticalScalerGo(taps)
 }
+
+func getHorizontalRingScaler(asm bool) ringScaler {
+	return h8ringGo
+}
+
+func getVerticalRingScaler(asm bool) ringScaler {
+	return v8ringGo
+}
+
+func getFastScaler(period, step, pack int, asm bool) scaler {
+	return nil
+}
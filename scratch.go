@@ -0,0 +1,98 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+import (
+	"sync"
+)
+
+// freeList keeps released scratch values for reuse, so that concurrent calls
+// never share mutable buffers
+type freeList struct {
+	lock  sync.Mutex
+	items []interface{}
+	alloc func() interface{}
+}
+
+// get returns a released value, or a new one
+func (f *freeList) get() interface{} {
+	f.lock.Lock()
+	n := len(f.items)
+	if n == 0 {
+		f.lock.Unlock()
+		return f.alloc()
+	}
+	v := f.items[n-1]
+	f.items[n-1] = nil
+	f.items = f.items[:n-1]
+	f.lock.Unlock()
+	return v
+}
+
+// put releases v for later calls
+func (f *freeList) put(v interface{}) {
+	f.lock.Lock()
+	f.items = append(f.items, v)
+	f.lock.Unlock()
+}
+
+// scratch holds intermediate planes used by a single call
+type scratch struct {
+	planes  []*Plane
+	src     [maxPlanes]Plane    // input planes, only used by converters
+	dst     [maxPlanes]Plane    // output planes, only used by converters
+	jobs    [maxPlanes]planeJob // plane jobs, only used by converters
+	bands   []bandJob           // band jobs, only used by stripers
+	sharpen []sharpenJob        // sharpen jobs, only used by sharpeners
+	group   sync.WaitGroup      // waits for jobs
+}
+
+// newScratch copies template planes and allocates their data
+// nil templates stay nil and identical templates share the same copy
+func newScratch(templates []*Plane) *scratch {
+	s := &scratch{
+		planes: make([]*Plane, len(templates)),
+	}
+	copies := map[*Plane]*Plane{}
+	unique := []*Plane{}
+	for i, p := range templates {
+		if p == nil {
+			continue
+		}
+		c, ok := copies[p]
+		if !ok {
+			v := *p
+			c = &v
+			copies[p] = c
+			unique = append(unique, c)
+		}
+		s.planes[i] = c
+	}
+	allocPlanes(unique)
+	return s
+}
+
+// allocPlanes sets data on every non-nil plane from a single buffer
+func allocPlanes(planes []*Plane) {
+	size := 0
+	for _, p := range planes {
+		if p != nil {
+			size += p.Pitch * p.Height
+		}
+	}
+	if size == 0 {
+		return
+	}
+	buffer := make([]byte, size)
+	idx := 0
+	for _, p := range planes {
+		if p == nil {
+			continue
+		}
+		size := p.Pitch*(p.Height-1) + p.Width*p.Pack
+		p.Data = buffer[idx : idx+size]
+		idx += p.Pitch * p.Height
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+import (
+	"fmt"
+)
+
+// Sharpen is an unsharp mask configuration, applied on resized planes
+type Sharpen struct {
+	Amount    float64 // sharpening strength, 0 disables sharpening
+	Radius    float64 // gaussian blur standard deviation in pixels
+	Threshold int     // minimal difference with the blurred pixel
+}
+
+func (s *Sharpen) check() error {
+	if s.Amount < 0 {
+		return fmt.Errorf("invalid sharpen amount %v", s.Amount)
+	}
+	if s.Amount > 0 && s.Radius <= 0 {
+		return fmt.Errorf("invalid sharpen radius %v", s.Radius)
+	}
+	return nil
+}
+
+const (
+	// sharpenRows is the number of output rows sharpened at once
+	sharpenRows = 32
+)
+
+// sharpener runs the last pass of a plane & applies the unsharp mask on its
+// rows strip by strip, while they are still in cache
+type sharpener struct {
+	unsharp    *[1 << 16]byte // sharpened pixels by pixel << 8 | blurred pixel
+	threads    int
+	interlaced bool
+	pool       *Pool
+	wrez       *context    // horizontal blur
+	hrez       [2]*context // vertical blur of frames, or of top & bottom fields
+	last       Resizer     // last pass, nil for copies & strips
+	rez        *context    // last pass when it resizes rows
+	strip      *striper    // strip passes, nil otherwise
+	full       bool        // whether the last pass must resize full planes
+	scratch    freeList    // strips for every job & full plane
+}
+
+func newSharpener(cfg *Sharpen, width, height, pack, threads int, interlaced, disableAsm bool, pool *Pool) *sharpener {
+	filter := NewGaussianFilter(cfg.Radius)
+	s := &sharpener{
+		unsharp:    newUnsharpTable(int(cfg.Amount*(1<<Bits)+0.5), cfg.Threshold),
+		threads:    max(1, min(threads, height)),
+		interlaced: interlaced,
+		pool:       pool,
+	}
+	for i := range s.hrez {
+		h := height
+		if interlaced {
+			h = (height + 1 - i) >> 1
+		}
+		s.hrez[i] = NewResize(&ResizerConfig{
+			Depth:      8,
+			Input:      h,
+			Output:     h,
+			Vertical:   true,
+			Pack:       pack,
+			Threads:    1,
+			DisableAsm: disableAsm,
+		}, filter).(*context)
+	}
+	s.wrez = NewResize(&ResizerConfig{
+		Depth:      8,
+		Input:      width,
+		Output:     width,
+		Vertical:   false,
+		Pack:       pack,
+		Threads:    1,
+		DisableAsm: disableAsm,
+	}, filter).(*context)
+	rows := 0
+	for _, v := range s.hrez {
+		for y := 0; y < v.cfg.Output; y++ {
+			top, bottom := v.inputRows(y, min(sharpenRows, v.cfg.Output-y))
+			rows = max(rows, bottom-top)
+		}
+	}
+	p := &Plane{
+		Width:  width,
+		Height: height,
+		Pitch:  align(width*pack, 16),
+		Pack:   pack,
+	}
+	jobs := s.threads << bin(interlaced)
+	s.scratch.alloc = func() interface{} {
+		planes := []*Plane{}
+		for i := 0; i < jobs; i++ {
+			src, vblur, hblur := *p, *p, *p
+			src.Height = rows
+			vblur.Height = min(sharpenRows, height)
+			hblur.Height = vblur.Height
+			planes = append(planes, &src, &vblur, &hblur)
+		}
+		if s.full {
+			planes = append(planes, p)
+		}
+		buf := newScratch(planes)
+		buf.sharpen = make([]sharpenJob, jobs)
+		return buf
+	}
+	return s
+}
+
+// attach sets the last pass of the plane, either strips, a resizer, or nil
+// for copies
+func (s *sharpener) attach(last Resizer, strip *striper) {
+	if strip != nil {
+		s.strip = strip
+		s.full = s.interlaced
+		return
+	}
+	if last == nil {
+		return
+	}
+	s.last = last
+	c, ok := getRowResizer(last).(*context)
+	// field rows cannot be resized by vertical passes
+	s.full = !ok || s.interlaced && c.cfg.Vertical
+	if !s.full {
+		s.rez = c
+	}
+}
+
+// rowSource produces rows of the plane to sharpen
+type rowSource struct {
+	src   Plane
+	rez   *context // resizes rows of src, copies them when nil
+	strip *striper // resizes rows of src through mid when set
+	mid   *Plane
+}
+
+// rows writes rows [y, y+rows) into dst
+func (r *rowSource) rows(dst []byte, y, rows, dp int) {
+	p := &r.src
+	switch {
+	case r.strip != nil:
+		r.strip.resizeBand(dst, dp, p, r.mid, y, y+rows, nil)
+	case r.rez != nil && r.rez.cfg.Vertical:
+		r.rez.resizeRows(dst, p.Data, p.Width, y, rows, dp, p.Pitch)
+	case r.rez != nil:
+		r.rez.resizeRows(dst, p.Data[p.Pitch*y:], p.Width, y, rows, dp, p.Pitch)
+	default:
+		copyPlane(dst, p.Data[p.Pitch*y:], p.Width*p.Pack, rows, dp, p.Pitch)
+	}
+}
+
+// sharpenJob sharpens a band of rows
+type sharpenJob struct {
+	s            *sharpener
+	dst          Plane
+	src          rowSource
+	hrez         *context
+	rows         *Plane // source rows read by the vertical blur
+	vblur, hblur *Plane
+	y, last      int
+	done         <-chan struct{}
+}
+
+func (j *sharpenJob) run() {
+	j.s.sharpenBand(&j.dst, &j.src, j.hrez, j.rows, j.vblur, j.hblur, j.y, j.last, j.done)
+}
+
+// getField returns the rows of field in p
+func getField(p *Plane, field int) Plane {
+	f := *p
+	f.Height = (p.Height + 1 - field) >> 1
+	f.Data = p.Data[p.Pitch*field:]
+	f.Pitch *= 2
+	f.Data = f.Data[:f.Pitch*(f.Height-1)+f.Width*f.Pack]
+	return f
+}
+
+// Sharpen runs the last pass of a plane from src into dst & applies the
+// unsharp mask on its rows, unless done is closed
+func (s *sharpener) Sharpen(dst, src *Plane, done <-chan struct{}) {
+	buf := s.scratch.get().(*scratch)
+	r := rowSource{src: *src, rez: s.rez, strip: s.strip}
+	if s.full {
+		out := buf.planes[len(buf.planes)-1]
+		resizeLast(done, out, src, s.last, s.strip, nil)
+		r = rowSource{src: *out}
+	}
+	var mids *scratch
+	if r.strip != nil {
+		mids = r.strip.scratch.get().(*scratch)
+	}
+	fields := 1 + int(bin(s.interlaced))
+	n := 0
+	for f := 0; f < fields; f++ {
+		d, fr := *dst, r
+		if s.interlaced {
+			d, fr.src = getField(dst, f), getField(&r.src, f)
+		}
+		nh := (d.Height + s.threads - 1) / s.threads
+		for i := 0; i < s.threads; i++ {
+			y := nh * i
+			if y >= d.Height || canceled(done) {
+				break
+			}
+			if mids != nil {
+				fr.mid = mids.planes[i]
+			}
+			j := &buf.sharpen[n]
+			*j = sharpenJob{
+				s:     s,
+				dst:   d,
+				src:   fr,
+				hrez:  s.hrez[f],
+				rows:  buf.planes[n*3],
+				vblur: buf.planes[n*3+1],
+				hblur: buf.planes[n*3+2],
+				y:     y,
+				last:  min(y+nh, d.Height),
+				done:  done,
+			}
+			dispatch(s.pool, &buf.group, s.threads, j)
+			n++
+		}
+	}
+	buf.group.Wait()
+	for i := range buf.sharpen {
+		buf.sharpen[i] = sharpenJob{}
+	}
+	if mids != nil {
+		r.strip.scratch.put(mids)
+	}
+	s.scratch.put(buf)
+}
+
+// sharpenBand sharpens rows [y, last) of dst strip by strip, source rows
+// shared by consecutive strips are kept instead of produced again
+func (s *sharpener) sharpenBand(dst *Plane, src *rowSource, hrez *context, rows, vblur, hblur *Plane, y, last int, done <-chan struct{}) {
+	width := dst.Width * dst.Pack
+	top, bottom := 0, 0 // source rows held in rows
+	for ; y < last && !canceled(done); y += sharpenRows {
+		n := min(sharpenRows, last-y)
+		next, end := hrez.inputRows(y, n)
+		keep := max(0, bottom-next)
+		if keep > 0 {
+			copyPlane(rows.Data, rows.Data[rows.Pitch*(next-top):], width, keep, rows.Pitch, rows.Pitch)
+		}
+		src.rows(rows.Data[rows.Pitch*keep:], next+keep, end-next-keep, rows.Pitch)
+		top, bottom = next, end
+		hrez.resizeFrom(vblur.Data, rows.Data, dst.Width, y, n, vblur.Pitch, rows.Pitch)
+		s.wrez.resizeRows(hblur.Data, vblur.Data, dst.Width, 0, n, hblur.Pitch, vblur.Pitch)
+		unsharpRows(dst.Data[dst.Pitch*y:], rows.Data[rows.Pitch*(y-top):], hblur.Data,
+			width, n, dst.Pitch, rows.Pitch, hblur.Pitch, s.unsharp)
+	}
+}
+
+// newUnsharpTable returns every pixel pushed away from every blurred value
+// by amount, when they differ by at least threshold
+func newUnsharpTable(amount, threshold int) *[1 << 16]byte {
+	t := &[1 << 16]byte{}
+	for i := range t {
+		v, b := i>>8, i&0xFF
+		diff := v - b
+		if diff >= threshold || -diff >= threshold {
+			v += (diff*amount + 1<<(Bits-1)) >> Bits
+		}
+		t[i] = u8(v)
+	}
+	return t
+}
+
+// unsharpRows writes src pixels sharpened with their blurred value into dst
+func unsharpRows(dst, src, blur []byte, width, height, dp, sp, bp int, unsharp *[1 << 16]byte) {
+	di := 0
+	si := 0
+	bi := 0
+	for y := 0; y < height; y++ {
+		d := dst[di : di+width]
+		b := blur[bi : bi+width]
+		for x, v := range src[si : si+width] {
+			d[x] = unsharp[uint16(v)<<8|uint16(b[x])]
+		}
+		di += dp
+		si += sp
+		bi += bp
+	}
+}
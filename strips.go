@@ -0,0 +1,163 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+// rowResizer is implemented by resizers able to resize a band of rows on
+// the calling goroutine
+type rowResizer interface {
+	// resizeRows resizes rows [y, y+rows) of the output into dst
+	// width = input width in pixels, only used by vertical resizers
+	// y = first output row, only used by vertical resizers
+	resizeRows(dst, src []byte, width, y, rows, dp, sp int)
+}
+
+// getRowResizer returns r as a rowResizer if it supports partial resizes
+func getRowResizer(r Resizer) rowResizer {
+	c, ok := r.(*context)
+	if !ok || c.cfg.Vertical && c.cfg.Interlaced {
+		return nil
+	}
+	return c
+}
+
+func (c *context) resizeRows(dst, src []byte, width, y, rows, dp, sp int) {
+	if c.cfg.Vertical {
+		src = src[sp*c.starts[y]:]
+	}
+	c.resizeFrom(dst, src, width, y, rows, dp, sp)
+}
+
+// resizeFrom is resizeRows with vertical src starting at the first input row
+// of output row y
+func (c *context) resizeFrom(dst, src []byte, width, y, rows, dp, sp int) {
+	k := &c.kernels[0]
+	pk := c.cfg.Pack
+	cof, off := k.coeffs, k.offsets
+	if c.cfg.Vertical {
+		cof = cof[y*k.size*k.cofscale : (y+rows)*k.size*k.cofscale]
+		off = off[y : y+rows]
+	} else {
+		width = c.cfg.Output
+	}
+	dst = dst[:dp*(rows-1)+width*pk]
+	if c.band != nil {
+		c.band(dst, src, y*int(bin(c.cfg.Vertical)), width*pk, rows, dp, sp)
+		return
+	}
+	c.scaler(dst, src, cof, off, k.size, width*pk, rows, dp, sp)
+}
+
+// inputRows returns input rows [top, bottom) seen by output rows [y, y+rows)
+// of a vertical resizer, top is where resizeFrom expects src
+func (c *context) inputRows(y, rows int) (int, int) {
+	k := &c.kernels[0]
+	last := y + rows - 1
+	return c.starts[y], min(c.starts[last]+int(k.offsets[last])+k.size, c.cfg.Input)
+}
+
+// getStarts returns the first input row of every output row from vertical
+// kernel offsets
+func getStarts(offsets []int16) []int {
+	starts := make([]int, len(offsets))
+	sum := 0
+	for i, off := range offsets {
+		starts[i] = sum
+		sum += int(off)
+	}
+	return starts
+}
+
+// striper chains vertical & horizontal passes on small strips of rows, so
+// intermediate rows stay in cache instead of going through a full plane
+type striper struct {
+	rows    int // rows per strip
+	threads int
+	pool    *Pool
+	hrez    rowResizer
+	wrez    rowResizer
+	scratch freeList // one strip plane per thread
+}
+
+// newStriper returns a striper for hrez & wrez, or nil if one of them cannot
+// resize partial rows
+func newStriper(hrez, wrez Resizer, rows, width, height, pack, threads int, pool *Pool) *striper {
+	h, w := getRowResizer(hrez), getRowResizer(wrez)
+	if h == nil || w == nil {
+		return nil
+	}
+	s := &striper{
+		rows:    min(rows, height),
+		threads: max(1, min(threads, height)),
+		pool:    pool,
+		hrez:    h,
+		wrez:    w,
+	}
+	strips := []*Plane{}
+	for i := 0; i < s.threads; i++ {
+		strips = append(strips, &Plane{
+			Width:  width,
+			Height: s.rows,
+			Pitch:  align(width*pack, 16),
+			Pack:   pack,
+		})
+	}
+	s.scratch.alloc = func() interface{} {
+		buf := newScratch(strips)
+		buf.bands = make([]bandJob, len(strips))
+		return buf
+	}
+	return s
+}
+
+// bandJob resizes a band of output rows
+type bandJob struct {
+	s     *striper
+	dst   *Plane
+	src   *Plane
+	strip *Plane
+	y     int
+	last  int
+	done  <-chan struct{}
+}
+
+func (j *bandJob) run() {
+	j.s.resizeBand(j.dst.Data[j.dst.Pitch*j.y:], j.dst.Pitch, j.src, j.strip, j.y, j.last, j.done)
+}
+
+// Resize resizes src into dst, splitting output rows between threads
+// Stops between strips once done is closed
+func (s *striper) Resize(dst, src *Plane, done <-chan struct{}) {
+	buf := s.scratch.get().(*scratch)
+	nh := (dst.Height + s.threads - 1) / s.threads
+	for i := range buf.bands {
+		y := nh * i
+		j := &buf.bands[i]
+		*j = bandJob{
+			s:     s,
+			dst:   dst,
+			src:   src,
+			strip: buf.planes[i],
+			y:     y,
+			last:  min(y+nh, dst.Height),
+			done:  done,
+		}
+		dispatch(s.pool, &buf.group, s.threads, j)
+	}
+	buf.group.Wait()
+	for i := range buf.bands {
+		buf.bands[i] = bandJob{}
+	}
+	s.scratch.put(buf)
+}
+
+// resizeBand resizes output rows [y, last) strip by strip into dst, which
+// starts at row y
+func (s *striper) resizeBand(dst []byte, dp int, src, strip *Plane, y, last int, done <-chan struct{}) {
+	for i := y; i < last && !canceled(done); i += s.rows {
+		rows := min(s.rows, last-i)
+		s.hrez.resizeRows(strip.Data, src.Data, src.Width, i, rows, strip.Pitch, src.Pitch)
+		s.wrez.resizeRows(dst[dp*(i-y):], strip.Data, src.Width, i, rows, dp, strip.Pitch)
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+/*
+Package tiles generates Deep Zoom (DZI) tile pyramids.
+
+Every zoom level is cut into fixed-size tiles, optionally overlapping their
+neighbours, and written to a Sink. Level N-1 is the source image, and each
+lower level is resized from the previous one with rez, down to a single
+pixel at level 0. Every level keeps the source image format. Tiles of the
+source level are views into the source, so it is never copied.
+
+	err := tiles.Generate(sink, img, &tiles.Config{TileSize: 254, Overlap: 1})
+*/
+package tiles
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/bamiaux/rez"
+)
+
+const (
+	// DefaultTileSize is the tile size used when none is configured
+	DefaultTileSize = 254
+)
+
+// Tile is a single tile of a zoom level
+type Tile struct {
+	Level int             // zoom level, 0 is the 1x1 pixel level
+	Col   int             // tile column
+	Row   int             // tile row
+	Scale int             // source pixels per level pixel, a power of two
+	Rect  image.Rectangle // tile bounds in level pixels, including overlap
+	Image image.Image     // tile pixels, only valid during WriteTile
+}
+
+// Sink is an interface receiving generated tiles
+// WriteTile is called concurrently from multiple goroutines
+type Sink interface {
+	WriteTile(tile *Tile) error
+}
+
+// Config is a configuration used with Generate
+type Config struct {
+	TileSize int        // tile size in pixels without overlap [default=254]
+	Overlap  int        // overlap in pixels on every tile side
+	Filter   rez.Filter // filter used to resize levels [default=bicubic]
+	Threads  int        // number of concurrent tile writes [default=GOMAXPROCS]
+}
+
+// Levels returns the number of zoom levels for an image size
+func Levels(width, height int) int {
+	n := 1
+	for s := max(width, height); s > 1; s = (s + 1) >> 1 {
+		n++
+	}
+	return n
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// WriteDzi writes the DZI descriptor of an image with the input size
+// format = tile image format extension, like "jpg" or "png"
+func WriteDzi(w io.Writer, width, height int, format string, cfg *Config) error {
+	c := getConfig(cfg)
+	_, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<Image xmlns="http://schemas.microsoft.com/deepzoom/2008" Format="%v" Overlap="%v" TileSize="%v">
+  <Size Width="%v" Height="%v"/>
+</Image>
+`, format, c.Overlap, c.TileSize, width, height)
+	return err
+}
+
+func getConfig(cfg *Config) Config {
+	c := Config{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.TileSize < 1 {
+		c.TileSize = DefaultTileSize
+	}
+	if c.Filter == nil {
+		c.Filter = rez.NewBicubicFilter()
+	}
+	if c.Threads < 1 {
+		c.Threads = runtime.GOMAXPROCS(0)
+	}
+	return c
+}
+
+// Generate cuts src into tiles at every zoom level and writes them to sink
+// Returns the first error from resizing or from sink
+func Generate(sink Sink, src image.Image, cfg *Config) error {
+	c := getConfig(cfg)
+	if c.Overlap < 0 {
+		return fmt.Errorf("invalid overlap %v", c.Overlap)
+	}
+	b := src.Bounds()
+	levels := Levels(b.Dx(), b.Dy())
+	img := src
+	for level := levels - 1; level >= 0; level-- {
+		if level != levels-1 {
+			next, err := shrink(img, c.Filter, c.Threads)
+			if err != nil {
+				return err
+			}
+			img = next
+		}
+		err := writeTiles(sink, img, level, 1<<uint(levels-1-level), &c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+func writeTiles(sink Sink, img image.Image, level, scale int, c *Config) error {
+	sub, ok := img.(subImager)
+	if !ok {
+		return fmt.Errorf("unable to cut %T into tiles", img)
+	}
+	b := img.Bounds()
+	cols := (b.Dx() + c.TileSize - 1) / c.TileSize
+	rows := (b.Dy() + c.TileSize - 1) / c.TileSize
+	jobs := make(chan *Tile)
+	errs := make(chan error, c.Threads)
+	group := sync.WaitGroup{}
+	for i := 0; i < c.Threads; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			var err error
+			for tile := range jobs {
+				if err == nil {
+					r := tile.Rect.Add(b.Min)
+					tile.Image = sub.SubImage(r)
+					err = sink.WriteTile(tile)
+				}
+			}
+			errs <- err
+		}()
+	}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			r := image.Rect(
+				col*c.TileSize-c.Overlap, row*c.TileSize-c.Overlap,
+				(col+1)*c.TileSize+c.Overlap, (row+1)*c.TileSize+c.Overlap)
+			jobs <- &Tile{
+				Level: level,
+				Col:   col,
+				Row:   row,
+				Scale: scale,
+				Rect:  r.Intersect(image.Rect(0, 0, b.Dx(), b.Dy())),
+			}
+		}
+	}
+	close(jobs)
+	group.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newImage allocates an image with the format of img and the size of d
+func newImage(img image.Image, d *rez.Descriptor) (image.Image, error) {
+	if _, ok := img.(*image.NRGBA); ok {
+		// rez allocates rgba images for every packed format
+		return rez.NewNRGBA(image.Rect(0, 0, d.Width, d.Height)), nil
+	}
+	return rez.NewImage(d)
+}
+
+// resizable returns whether rez can resize every plane of a conversion
+func resizable(cfg *rez.ConverterConfig) bool {
+	for _, d := range []*rez.Descriptor{&cfg.Input, &cfg.Output} {
+		for i := 0; i < d.Planes; i++ {
+			if d.GetWidth(i) < 2 || d.GetHeight(i) < 2 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// shrink halves img dimensions, rounding them up
+func shrink(img image.Image, filter rez.Filter, threads int) (image.Image, error) {
+	cfg, err := rez.PrepareConversion(img, img)
+	if err != nil {
+		return nil, err
+	}
+	d := cfg.Input
+	d.Width, d.Height = (d.Width+1)>>1, (d.Height+1)>>1
+	dst, err := newImage(img, &d)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err = rez.PrepareConversion(dst, img)
+	if err != nil {
+		return nil, err
+	}
+	if !resizable(cfg) {
+		average(getPlanes(dst, &cfg.Output), getPlanes(img, &cfg.Input))
+		return dst, nil
+	}
+	cfg.Threads = threads
+	converter, err := rez.NewConverter(cfg, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dst, converter.Convert(dst, img)
+}
+
+// plane is one plane of an image
+type plane struct {
+	data   []byte
+	width  int // width in pixels
+	height int // height in pixels
+	pitch  int // bytes per row
+	pack   int // bytes per pixel
+}
+
+// getPlanes returns planes of img described by d
+func getPlanes(img image.Image, d *rez.Descriptor) []plane {
+	b := img.Bounds()
+	var data [][]byte
+	var pitches []int
+	switch t := img.(type) {
+	case *image.YCbCr:
+		y, c := t.YOffset(b.Min.X, b.Min.Y), t.COffset(b.Min.X, b.Min.Y)
+		data = [][]byte{t.Y[y:], t.Cb[c:], t.Cr[c:]}
+		pitches = []int{t.YStride, t.CStride, t.CStride}
+	case *image.RGBA:
+		data = [][]byte{t.Pix[t.PixOffset(b.Min.X, b.Min.Y):]}
+		pitches = []int{t.Stride}
+	case *image.NRGBA:
+		data = [][]byte{t.Pix[t.PixOffset(b.Min.X, b.Min.Y):]}
+		pitches = []int{t.Stride}
+	case *image.Gray:
+		data = [][]byte{t.Pix[t.PixOffset(b.Min.X, b.Min.Y):]}
+		pitches = []int{t.Stride}
+	}
+	planes := make([]plane, len(data))
+	for i := range planes {
+		planes[i] = plane{
+			data:   data[i],
+			width:  d.GetWidth(i),
+			height: d.GetHeight(i),
+			pitch:  pitches[i],
+			pack:   d.Pack,
+		}
+	}
+	return planes
+}
+
+// average halves tiny images which rez cannot resize, plane by plane
+// Planes already one pixel wide or high keep that size
+func average(dst, src []plane) {
+	for i, d := range dst {
+		averagePlane(d, src[i])
+	}
+}
+
+func averagePlane(dst, src plane) {
+	for y := 0; y < dst.height; y++ {
+		for x := 0; x < dst.width; x++ {
+			for c := 0; c < dst.pack; c++ {
+				sum, n := 0, 0
+				for j := y * 2; j < min(y*2+2, src.height); j++ {
+					for i := x * 2; i < min(x*2+2, src.width); i++ {
+						sum += int(src.data[src.pitch*j+src.pack*i+c])
+						n++
+					}
+				}
+				dst.data[dst.pitch*y+dst.pack*x+c] = byte((sum + n/2) / n)
+			}
+		}
+	}
+}
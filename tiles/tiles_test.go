@@ -0,0 +1,137 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package tiles
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memorySink struct {
+	lock    sync.Mutex
+	tiles   map[string]image.Rectangle
+	formats map[string]bool // tile image types
+	fail    int
+}
+
+func (s *memorySink) WriteTile(tile *Tile) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if tile.Image.Bounds().Size() != tile.Rect.Size() {
+		return fmt.Errorf("invalid tile image %v", tile.Image.Bounds())
+	}
+	if s.fail > 0 && tile.Level == s.fail {
+		return fmt.Errorf("sink failure")
+	}
+	s.tiles[fmt.Sprintf("%v/%v_%v", tile.Level, tile.Col, tile.Row)] = tile.Rect
+	if s.formats != nil {
+		s.formats[fmt.Sprintf("%T", tile.Image)] = true
+	}
+	return nil
+}
+
+func TestGenerate(t *testing.T) {
+	if Levels(1, 1) != 1 || Levels(2, 1) != 2 || Levels(301, 199) != 10 {
+		t.Fatalf("invalid levels")
+	}
+	for _, src := range []image.Image{
+		image.NewYCbCr(image.Rect(3, 5, 304, 204), image.YCbCrSubsampleRatio420),
+		image.NewRGBA(image.Rect(0, 0, 301, 199)),
+		image.NewGray(image.Rect(0, 0, 301, 199)),
+		image.NewNRGBA(image.Rect(0, 0, 301, 199)),
+	} {
+		sink := &memorySink{tiles: map[string]image.Rectangle{}, formats: map[string]bool{}}
+		err := Generate(sink, src, &Config{TileSize: 64, Overlap: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 5x4 + 3x2 + 2x1 + 1x1 tiles with 6 single tile levels
+		if len(sink.tiles) != 20+6+2+1+6 {
+			t.Fatalf("invalid tile count %v", len(sink.tiles))
+		}
+		want := map[string]image.Rectangle{
+			"9/0_0": image.Rect(0, 0, 65, 65),
+			"9/1_1": image.Rect(63, 63, 129, 129),
+			"9/4_3": image.Rect(255, 191, 301, 199),
+			"8/2_1": image.Rect(127, 63, 151, 100),
+			"0/0_0": image.Rect(0, 0, 1, 1),
+		}
+		for k, v := range want {
+			if sink.tiles[k] != v {
+				t.Fatalf("invalid tile %v %v, want %v", k, sink.tiles[k], v)
+			}
+		}
+		// every level keeps the source format, down to 1x1 levels
+		if len(sink.formats) != 1 || !sink.formats[fmt.Sprintf("%T", src)] {
+			t.Fatalf("invalid tile formats %v for %T", sink.formats, src)
+		}
+		sink.fail = 3
+		err = Generate(sink, src, &Config{TileSize: 64})
+		if err == nil {
+			t.Fatalf("sink error ignored")
+		}
+	}
+	sink := &memorySink{tiles: map[string]image.Rectangle{}}
+	err := Generate(sink, image.NewGray(image.Rect(0, 0, 3, 2)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.tiles) != 3 {
+		t.Fatalf("invalid tile count %v", len(sink.tiles))
+	}
+	buf := &bytes.Buffer{}
+	err = WriteDzi(buf, 301, 199, "png", &Config{Overlap: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `TileSize="254"`) {
+		t.Fatalf("invalid dzi %v", buf.String())
+	}
+}
+
+// levelSink keeps the single tile of every level
+type levelSink struct {
+	lock   sync.Mutex
+	levels map[int]image.Image
+}
+
+func (s *levelSink) WriteTile(tile *Tile) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.levels[tile.Level] = tile.Image
+	return nil
+}
+
+func TestAverage(t *testing.T) {
+	// tiny levels average each plane, including one pixel chroma planes
+	src := image.NewYCbCr(image.Rect(0, 0, 3, 3), image.YCbCrSubsampleRatio420)
+	for i := range src.Y {
+		src.Y[i] = 100
+	}
+	for i := range src.Cb {
+		src.Cb[i] = 50
+		src.Cr[i] = 200
+	}
+	src.Y[0] = 104
+	sink := &levelSink{levels: map[int]image.Image{}}
+	err := Generate(sink, src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.levels) != 3 {
+		t.Fatalf("invalid level count %v", len(sink.levels))
+	}
+	for level, want := range []uint8{100, 101} {
+		img := sink.levels[level].(*image.YCbCr)
+		c := img.YCbCrAt(0, 0)
+		if c.Y != want || c.Cb != 50 || c.Cr != 200 {
+			t.Fatalf("invalid level %v pixel %v", level, c)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2014 Benoît Amiaux. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rez
+
+import (
+	"fmt"
+)
+
+// verifier converts images a second time with Go scalers only, and compares
+// both outputs
+type verifier struct {
+	ref     *converterContext // Go only converter
+	scratch freeList          // reference output planes
+}
+
+func newVerifier(cfg *ConverterConfig, filter Filter) (*verifier, error) {
+	ref := *cfg
+	ref.DisableAsm = true
+	ref.Verify = false
+	ctx, err := newConverterContext(&ref, filter)
+	if err != nil {
+		return nil, err
+	}
+	ctx.allocate()
+	v := &verifier{
+		ref: ctx,
+	}
+	d := &cfg.Output
+	planes := []*Plane{}
+	for i := 0; i < d.Planes; i++ {
+		w, h := d.GetWidth(i), d.fieldHeight(i)
+		planes = append(planes, &Plane{
+			Width:  w,
+			Height: h,
+			Pitch:  align(w*d.Pack, 16),
+			Pack:   d.Pack,
+		})
+	}
+	v.scratch.alloc = func() interface{} {
+		return newScratch(planes)
+	}
+	return v, nil
+}
+
+// check converts src with Go scalers and returns an error on the first pixel
+// of dst which differs
+func (v *verifier) check(done <-chan struct{}, dst, src []Plane) error {
+	out := v.scratch.get().(*scratch)
+	defer v.scratch.put(out)
+	s := v.ref.scratch.get().(*scratch)
+	defer v.ref.release(s)
+	ref := s.dst[:len(dst)]
+	for i := range ref {
+		ref[i] = *out.planes[i]
+	}
+	v.ref.convertPlanes(done, s, ref, src)
+	if canceled(done) {
+		return nil
+	}
+	for i := range dst {
+		if err := comparePlanes(i, &dst[i], &ref[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// comparePlanes returns an error on the first pixel of got which differs
+// from want
+func comparePlanes(plane int, got, want *Plane) error {
+	width := want.Width * want.Pack
+	gi := 0
+	wi := 0
+	for y := 0; y < want.Height; y++ {
+		g := got.Data[gi : gi+width]
+		for x, v := range want.Data[wi : wi+width] {
+			if g[x] != v {
+				return fmt.Errorf("asm & go mismatch on plane %v at pixel %v,%v channel %v: asm=%v go=%v",
+					plane, x/want.Pack, y, x%want.Pack, g[x], v)
+			}
+		}
+		gi += got.Pitch
+		wi += want.Pitch
+	}
+	return nil
+}
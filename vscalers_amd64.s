@@ -11,7 +11,7 @@ DATA	hbits_1<>+0x00(SB)/8, $0x0000200000002000
 DATA	hbits_1<>+0x08(SB)/8, $0x0000200000002000
 GLOBL	hbits_1<>(SB), 8, $16
 
-TEXT ·v8scale2Amd64(SB),4,$0-136
+TEXT ·v8scale2Amd64(SB),4,$8-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -45,7 +45,7 @@ yloop_1:
 		MOVQ	SI, R9
 		MOVQ	R12, CX
 		ORQ	CX, CX
-		JE	nomaxloop_2
+		JE	narrow_2
 maxloop_3:
 		MOVOU	(BP), X12
 		MOVOU	(SI), X0
@@ -79,12 +79,11 @@ maxloop_3:
 		ADDQ	$16, DI
 		SUBQ	$1, CX
 		JNE	maxloop_3
-nomaxloop_2:
 		MOVQ	R13, CX
 		SUBQ	R13, SI
 		SUBQ	R13, DI
 		ORQ	CX, CX
-		JE	nobackroll_4
+		JE	end_4
 		MOVOU	(BP), X12
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -115,7 +114,134 @@ nomaxloop_2:
 		MOVOU	X0, (DI)
 		ADDQ	$16, SI
 		ADDQ	$16, DI
-nobackroll_4:
+		JMP	end_4
+narrow_2:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	$1, count+-8(SP)
+loop_5:
+		PXOR	X4, X4
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_6
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X4
+skip1_6:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_7
+		SUBQ	$2, R8
+		PSLLO	$2, X4
+		PINSRW	$0, (AX)(R8*1), X4
+skip2_7:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_8
+		SUBQ	$4, R8
+		PSLLO	$4, X4
+		PINSRW	$0, (AX)(R8*1), X4
+		PINSRW	$1, 2(AX)(R8*1), X4
+skip4_8:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_9
+		PSLLO	$8, X4
+		PINSRW	$0, (AX), X4
+		PINSRW	$1, 2(AX), X4
+		PINSRW	$2, 4(AX), X4
+		PINSRW	$3, 6(AX), X4
+skip8_9:
+		ADDQ	BX, AX
+		PXOR	X7, X7
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_10
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X7
+skip1_10:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_11
+		SUBQ	$2, R8
+		PSLLO	$2, X7
+		PINSRW	$0, (AX)(R8*1), X7
+skip2_11:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_12
+		SUBQ	$4, R8
+		PSLLO	$4, X7
+		PINSRW	$0, (AX)(R8*1), X7
+		PINSRW	$1, 2(AX)(R8*1), X7
+skip4_12:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_13
+		PSLLO	$8, X7
+		PINSRW	$0, (AX), X7
+		PINSRW	$1, 2(AX), X7
+		PINSRW	$2, 4(AX), X7
+		PINSRW	$3, 6(AX), X7
+skip8_13:
+		ADDQ	BX, AX
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-8(SP)
+		JNE	loop_5
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, width+104(FP)
+		JE	skip8_14
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_14:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_15
+		MOVQ	X0, DX
+		MOVL	DX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_15:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_16
+		MOVQ	X0, DX
+		MOVW	DX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_16:
+		TESTQ	$1, width+104(FP)
+		JE	skip1_17
+		MOVQ	X0, DX
+		MOVB	DX, (DI)
+		ADDQ	$1, DI
+skip1_17:
+end_4:
 		ADDQ	R11, DI
 		ADDQ	$32, BP
 		ADDQ	$2, R10
@@ -123,7 +249,7 @@ nobackroll_4:
 		JNE	yloop_1
 		RET
 
-TEXT ·v8scale4Amd64(SB),4,$0-136
+TEXT ·v8scale4Amd64(SB),4,$8-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -134,10 +260,10 @@ TEXT ·v8scale4Amd64(SB),4,$0-136
 		MOVQ	CX, R12
 		MOVQ	DX, AX
 		ORQ	AX, AX
-		JE	norollback_5
+		JE	norollback_18
 		SUBQ	$16, DX
 		NEGQ	DX
-norollback_5:
+norollback_18:
 		MOVQ	DX, R13
 		MOVQ	off+72(FP), CX
 		MOVQ	CX, R10
@@ -148,7 +274,7 @@ norollback_5:
 		MOVQ	dst+0(FP), DI
 		MOVQ	cof+48(FP), BP
 		MOVQ	sp+128(FP), BX
-yloop_6:
+yloop_19:
 		MOVQ	R9, SI
 		MOVQ	R10, DX
 		MOVWQSX	(DX), AX
@@ -157,8 +283,8 @@ yloop_6:
 		MOVQ	SI, R9
 		MOVQ	R12, CX
 		ORQ	CX, CX
-		JE	nomaxloop_7
-maxloop_8:
+		JE	narrow_20
+maxloop_21:
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
 		MOVOU	(SI)(BX*2), X4
@@ -212,13 +338,12 @@ maxloop_8:
 		ADDQ	$16, SI
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	maxloop_8
-nomaxloop_7:
+		JNE	maxloop_21
 		MOVQ	R13, CX
 		SUBQ	R13, SI
 		SUBQ	R13, DI
 		ORQ	CX, CX
-		JE	nobackroll_9
+		JE	end_22
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
 		MOVOU	(SI)(BX*2), X4
@@ -271,15 +396,142 @@ nomaxloop_7:
 		MOVOU	X0, (DI)
 		ADDQ	$16, SI
 		ADDQ	$16, DI
-nobackroll_9:
+		JMP	end_22
+narrow_20:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	$2, count+-8(SP)
+loop_23:
+		PXOR	X4, X4
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_24
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X4
+skip1_24:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_25
+		SUBQ	$2, R8
+		PSLLO	$2, X4
+		PINSRW	$0, (AX)(R8*1), X4
+skip2_25:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_26
+		SUBQ	$4, R8
+		PSLLO	$4, X4
+		PINSRW	$0, (AX)(R8*1), X4
+		PINSRW	$1, 2(AX)(R8*1), X4
+skip4_26:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_27
+		PSLLO	$8, X4
+		PINSRW	$0, (AX), X4
+		PINSRW	$1, 2(AX), X4
+		PINSRW	$2, 4(AX), X4
+		PINSRW	$3, 6(AX), X4
+skip8_27:
+		ADDQ	BX, AX
+		PXOR	X7, X7
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_28
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X7
+skip1_28:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_29
+		SUBQ	$2, R8
+		PSLLO	$2, X7
+		PINSRW	$0, (AX)(R8*1), X7
+skip2_29:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_30
+		SUBQ	$4, R8
+		PSLLO	$4, X7
+		PINSRW	$0, (AX)(R8*1), X7
+		PINSRW	$1, 2(AX)(R8*1), X7
+skip4_30:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_31
+		PSLLO	$8, X7
+		PINSRW	$0, (AX), X7
+		PINSRW	$1, 2(AX), X7
+		PINSRW	$2, 4(AX), X7
+		PINSRW	$3, 6(AX), X7
+skip8_31:
+		ADDQ	BX, AX
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-8(SP)
+		JNE	loop_23
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, width+104(FP)
+		JE	skip8_32
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_32:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_33
+		MOVQ	X0, DX
+		MOVL	DX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_33:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_34
+		MOVQ	X0, DX
+		MOVW	DX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_34:
+		TESTQ	$1, width+104(FP)
+		JE	skip1_35
+		MOVQ	X0, DX
+		MOVB	DX, (DI)
+		ADDQ	$1, DI
+skip1_35:
+end_22:
 		ADDQ	R11, DI
 		ADDQ	$64, BP
 		ADDQ	$2, R10
 		SUBQ	$1, height+112(FP)
-		JNE	yloop_6
+		JNE	yloop_19
 		RET
 
-TEXT ·v8scale6Amd64(SB),4,$0-136
+TEXT ·v8scale6Amd64(SB),4,$8-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -290,10 +542,10 @@ TEXT ·v8scale6Amd64(SB),4,$0-136
 		MOVQ	CX, R12
 		MOVQ	DX, AX
 		ORQ	AX, AX
-		JE	norollback_10
+		JE	norollback_36
 		SUBQ	$16, DX
 		NEGQ	DX
-norollback_10:
+norollback_36:
 		MOVQ	DX, R13
 		MOVQ	off+72(FP), CX
 		MOVQ	CX, R10
@@ -304,7 +556,7 @@ norollback_10:
 		MOVQ	dst+0(FP), DI
 		MOVQ	cof+48(FP), BP
 		MOVQ	sp+128(FP), BX
-yloop_11:
+yloop_37:
 		MOVQ	R9, SI
 		MOVQ	R10, DX
 		MOVWQSX	(DX), AX
@@ -313,8 +565,8 @@ yloop_11:
 		MOVQ	SI, R9
 		MOVQ	R12, CX
 		ORQ	CX, CX
-		JE	nomaxloop_12
-maxloop_13:
+		JE	narrow_38
+maxloop_39:
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -389,13 +641,12 @@ maxloop_13:
 		ADDQ	$16, SI
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	maxloop_13
-nomaxloop_12:
+		JNE	maxloop_39
 		MOVQ	R13, CX
 		SUBQ	R13, SI
 		SUBQ	R13, DI
 		ORQ	CX, CX
-		JE	nobackroll_14
+		JE	end_40
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -469,15 +720,142 @@ nomaxloop_12:
 		MOVOU	X0, (DI)
 		ADDQ	$16, SI
 		ADDQ	$16, DI
-nobackroll_14:
+		JMP	end_40
+narrow_38:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	$3, count+-8(SP)
+loop_41:
+		PXOR	X4, X4
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_42
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X4
+skip1_42:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_43
+		SUBQ	$2, R8
+		PSLLO	$2, X4
+		PINSRW	$0, (AX)(R8*1), X4
+skip2_43:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_44
+		SUBQ	$4, R8
+		PSLLO	$4, X4
+		PINSRW	$0, (AX)(R8*1), X4
+		PINSRW	$1, 2(AX)(R8*1), X4
+skip4_44:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_45
+		PSLLO	$8, X4
+		PINSRW	$0, (AX), X4
+		PINSRW	$1, 2(AX), X4
+		PINSRW	$2, 4(AX), X4
+		PINSRW	$3, 6(AX), X4
+skip8_45:
+		ADDQ	BX, AX
+		PXOR	X7, X7
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_46
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X7
+skip1_46:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_47
+		SUBQ	$2, R8
+		PSLLO	$2, X7
+		PINSRW	$0, (AX)(R8*1), X7
+skip2_47:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_48
+		SUBQ	$4, R8
+		PSLLO	$4, X7
+		PINSRW	$0, (AX)(R8*1), X7
+		PINSRW	$1, 2(AX)(R8*1), X7
+skip4_48:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_49
+		PSLLO	$8, X7
+		PINSRW	$0, (AX), X7
+		PINSRW	$1, 2(AX), X7
+		PINSRW	$2, 4(AX), X7
+		PINSRW	$3, 6(AX), X7
+skip8_49:
+		ADDQ	BX, AX
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-8(SP)
+		JNE	loop_41
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, width+104(FP)
+		JE	skip8_50
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_50:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_51
+		MOVQ	X0, DX
+		MOVL	DX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_51:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_52
+		MOVQ	X0, DX
+		MOVW	DX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_52:
+		TESTQ	$1, width+104(FP)
+		JE	skip1_53
+		MOVQ	X0, DX
+		MOVB	DX, (DI)
+		ADDQ	$1, DI
+skip1_53:
+end_40:
 		ADDQ	R11, DI
 		ADDQ	$96, BP
 		ADDQ	$2, R10
 		SUBQ	$1, height+112(FP)
-		JNE	yloop_11
+		JNE	yloop_37
 		RET
 
-TEXT ·v8scale8Amd64(SB),4,$0-136
+TEXT ·v8scale8Amd64(SB),4,$8-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -488,10 +866,10 @@ TEXT ·v8scale8Amd64(SB),4,$0-136
 		MOVQ	CX, R12
 		MOVQ	DX, AX
 		ORQ	AX, AX
-		JE	norollback_15
+		JE	norollback_54
 		SUBQ	$16, DX
 		NEGQ	DX
-norollback_15:
+norollback_54:
 		MOVQ	DX, R13
 		MOVQ	off+72(FP), CX
 		MOVQ	CX, R10
@@ -502,7 +880,7 @@ norollback_15:
 		MOVQ	dst+0(FP), DI
 		MOVQ	cof+48(FP), BP
 		MOVQ	sp+128(FP), BX
-yloop_16:
+yloop_55:
 		MOVQ	R9, SI
 		MOVQ	R10, DX
 		MOVWQSX	(DX), AX
@@ -511,8 +889,8 @@ yloop_16:
 		MOVQ	SI, R9
 		MOVQ	R12, CX
 		ORQ	CX, CX
-		JE	nomaxloop_17
-maxloop_18:
+		JE	narrow_56
+maxloop_57:
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -607,13 +985,12 @@ maxloop_18:
 		ADDQ	$16, SI
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	maxloop_18
-nomaxloop_17:
+		JNE	maxloop_57
 		MOVQ	R13, CX
 		SUBQ	R13, SI
 		SUBQ	R13, DI
 		ORQ	CX, CX
-		JE	nobackroll_19
+		JE	end_58
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -707,15 +1084,142 @@ nomaxloop_17:
 		MOVOU	X0, (DI)
 		ADDQ	$16, SI
 		ADDQ	$16, DI
-nobackroll_19:
+		JMP	end_58
+narrow_56:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	$4, count+-8(SP)
+loop_59:
+		PXOR	X4, X4
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_60
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X4
+skip1_60:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_61
+		SUBQ	$2, R8
+		PSLLO	$2, X4
+		PINSRW	$0, (AX)(R8*1), X4
+skip2_61:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_62
+		SUBQ	$4, R8
+		PSLLO	$4, X4
+		PINSRW	$0, (AX)(R8*1), X4
+		PINSRW	$1, 2(AX)(R8*1), X4
+skip4_62:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_63
+		PSLLO	$8, X4
+		PINSRW	$0, (AX), X4
+		PINSRW	$1, 2(AX), X4
+		PINSRW	$2, 4(AX), X4
+		PINSRW	$3, 6(AX), X4
+skip8_63:
+		ADDQ	BX, AX
+		PXOR	X7, X7
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_64
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X7
+skip1_64:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_65
+		SUBQ	$2, R8
+		PSLLO	$2, X7
+		PINSRW	$0, (AX)(R8*1), X7
+skip2_65:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_66
+		SUBQ	$4, R8
+		PSLLO	$4, X7
+		PINSRW	$0, (AX)(R8*1), X7
+		PINSRW	$1, 2(AX)(R8*1), X7
+skip4_66:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_67
+		PSLLO	$8, X7
+		PINSRW	$0, (AX), X7
+		PINSRW	$1, 2(AX), X7
+		PINSRW	$2, 4(AX), X7
+		PINSRW	$3, 6(AX), X7
+skip8_67:
+		ADDQ	BX, AX
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-8(SP)
+		JNE	loop_59
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, width+104(FP)
+		JE	skip8_68
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_68:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_69
+		MOVQ	X0, DX
+		MOVL	DX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_69:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_70
+		MOVQ	X0, DX
+		MOVW	DX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_70:
+		TESTQ	$1, width+104(FP)
+		JE	skip1_71
+		MOVQ	X0, DX
+		MOVB	DX, (DI)
+		ADDQ	$1, DI
+skip1_71:
+end_58:
 		ADDQ	R11, DI
 		ADDQ	$128, BP
 		ADDQ	$2, R10
 		SUBQ	$1, height+112(FP)
-		JNE	yloop_16
+		JNE	yloop_55
 		RET
 
-TEXT ·v8scale10Amd64(SB),4,$0-136
+TEXT ·v8scale10Amd64(SB),4,$8-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -726,10 +1230,10 @@ TEXT ·v8scale10Amd64(SB),4,$0-136
 		MOVQ	CX, R12
 		MOVQ	DX, AX
 		ORQ	AX, AX
-		JE	norollback_20
+		JE	norollback_72
 		SUBQ	$16, DX
 		NEGQ	DX
-norollback_20:
+norollback_72:
 		MOVQ	DX, R13
 		MOVQ	off+72(FP), CX
 		MOVQ	CX, R10
@@ -740,7 +1244,7 @@ norollback_20:
 		MOVQ	dst+0(FP), DI
 		MOVQ	cof+48(FP), BP
 		MOVQ	sp+128(FP), BX
-yloop_21:
+yloop_73:
 		MOVQ	R9, SI
 		MOVQ	R10, DX
 		MOVWQSX	(DX), AX
@@ -749,8 +1253,8 @@ yloop_21:
 		MOVQ	SI, R9
 		MOVQ	R12, CX
 		ORQ	CX, CX
-		JE	nomaxloop_22
-maxloop_23:
+		JE	narrow_74
+maxloop_75:
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -865,13 +1369,12 @@ maxloop_23:
 		ADDQ	$16, SI
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	maxloop_23
-nomaxloop_22:
+		JNE	maxloop_75
 		MOVQ	R13, CX
 		SUBQ	R13, SI
 		SUBQ	R13, DI
 		ORQ	CX, CX
-		JE	nobackroll_24
+		JE	end_76
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -985,15 +1488,142 @@ nomaxloop_22:
 		MOVOU	X0, (DI)
 		ADDQ	$16, SI
 		ADDQ	$16, DI
-nobackroll_24:
+		JMP	end_76
+narrow_74:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	$5, count+-8(SP)
+loop_77:
+		PXOR	X4, X4
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_78
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X4
+skip1_78:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_79
+		SUBQ	$2, R8
+		PSLLO	$2, X4
+		PINSRW	$0, (AX)(R8*1), X4
+skip2_79:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_80
+		SUBQ	$4, R8
+		PSLLO	$4, X4
+		PINSRW	$0, (AX)(R8*1), X4
+		PINSRW	$1, 2(AX)(R8*1), X4
+skip4_80:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_81
+		PSLLO	$8, X4
+		PINSRW	$0, (AX), X4
+		PINSRW	$1, 2(AX), X4
+		PINSRW	$2, 4(AX), X4
+		PINSRW	$3, 6(AX), X4
+skip8_81:
+		ADDQ	BX, AX
+		PXOR	X7, X7
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_82
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X7
+skip1_82:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_83
+		SUBQ	$2, R8
+		PSLLO	$2, X7
+		PINSRW	$0, (AX)(R8*1), X7
+skip2_83:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_84
+		SUBQ	$4, R8
+		PSLLO	$4, X7
+		PINSRW	$0, (AX)(R8*1), X7
+		PINSRW	$1, 2(AX)(R8*1), X7
+skip4_84:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_85
+		PSLLO	$8, X7
+		PINSRW	$0, (AX), X7
+		PINSRW	$1, 2(AX), X7
+		PINSRW	$2, 4(AX), X7
+		PINSRW	$3, 6(AX), X7
+skip8_85:
+		ADDQ	BX, AX
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-8(SP)
+		JNE	loop_77
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, width+104(FP)
+		JE	skip8_86
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_86:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_87
+		MOVQ	X0, DX
+		MOVL	DX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_87:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_88
+		MOVQ	X0, DX
+		MOVW	DX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_88:
+		TESTQ	$1, width+104(FP)
+		JE	skip1_89
+		MOVQ	X0, DX
+		MOVB	DX, (DI)
+		ADDQ	$1, DI
+skip1_89:
+end_76:
 		ADDQ	R11, DI
 		ADDQ	$160, BP
 		ADDQ	$2, R10
 		SUBQ	$1, height+112(FP)
-		JNE	yloop_21
+		JNE	yloop_73
 		RET
 
-TEXT ·v8scale12Amd64(SB),4,$0-136
+TEXT ·v8scale12Amd64(SB),4,$8-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -1004,10 +1634,10 @@ TEXT ·v8scale12Amd64(SB),4,$0-136
 		MOVQ	CX, R12
 		MOVQ	DX, AX
 		ORQ	AX, AX
-		JE	norollback_25
+		JE	norollback_90
 		SUBQ	$16, DX
 		NEGQ	DX
-norollback_25:
+norollback_90:
 		MOVQ	DX, R13
 		MOVQ	off+72(FP), CX
 		MOVQ	CX, R10
@@ -1018,7 +1648,7 @@ norollback_25:
 		MOVQ	dst+0(FP), DI
 		MOVQ	cof+48(FP), BP
 		MOVQ	sp+128(FP), BX
-yloop_26:
+yloop_91:
 		MOVQ	R9, SI
 		MOVQ	R10, DX
 		MOVWQSX	(DX), AX
@@ -1027,8 +1657,8 @@ yloop_26:
 		MOVQ	SI, R9
 		MOVQ	R12, CX
 		ORQ	CX, CX
-		JE	nomaxloop_27
-maxloop_28:
+		JE	narrow_92
+maxloop_93:
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -1163,13 +1793,12 @@ maxloop_28:
 		ADDQ	$16, SI
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	maxloop_28
-nomaxloop_27:
+		JNE	maxloop_93
 		MOVQ	R13, CX
 		SUBQ	R13, SI
 		SUBQ	R13, DI
 		ORQ	CX, CX
-		JE	nobackroll_29
+		JE	end_94
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -1303,15 +1932,142 @@ nomaxloop_27:
 		MOVOU	X0, (DI)
 		ADDQ	$16, SI
 		ADDQ	$16, DI
-nobackroll_29:
+		JMP	end_94
+narrow_92:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	$6, count+-8(SP)
+loop_95:
+		PXOR	X4, X4
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_96
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X4
+skip1_96:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_97
+		SUBQ	$2, R8
+		PSLLO	$2, X4
+		PINSRW	$0, (AX)(R8*1), X4
+skip2_97:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_98
+		SUBQ	$4, R8
+		PSLLO	$4, X4
+		PINSRW	$0, (AX)(R8*1), X4
+		PINSRW	$1, 2(AX)(R8*1), X4
+skip4_98:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_99
+		PSLLO	$8, X4
+		PINSRW	$0, (AX), X4
+		PINSRW	$1, 2(AX), X4
+		PINSRW	$2, 4(AX), X4
+		PINSRW	$3, 6(AX), X4
+skip8_99:
+		ADDQ	BX, AX
+		PXOR	X7, X7
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_100
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X7
+skip1_100:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_101
+		SUBQ	$2, R8
+		PSLLO	$2, X7
+		PINSRW	$0, (AX)(R8*1), X7
+skip2_101:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_102
+		SUBQ	$4, R8
+		PSLLO	$4, X7
+		PINSRW	$0, (AX)(R8*1), X7
+		PINSRW	$1, 2(AX)(R8*1), X7
+skip4_102:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_103
+		PSLLO	$8, X7
+		PINSRW	$0, (AX), X7
+		PINSRW	$1, 2(AX), X7
+		PINSRW	$2, 4(AX), X7
+		PINSRW	$3, 6(AX), X7
+skip8_103:
+		ADDQ	BX, AX
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-8(SP)
+		JNE	loop_95
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, width+104(FP)
+		JE	skip8_104
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_104:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_105
+		MOVQ	X0, DX
+		MOVL	DX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_105:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_106
+		MOVQ	X0, DX
+		MOVW	DX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_106:
+		TESTQ	$1, width+104(FP)
+		JE	skip1_107
+		MOVQ	X0, DX
+		MOVB	DX, (DI)
+		ADDQ	$1, DI
+skip1_107:
+end_94:
 		ADDQ	R11, DI
 		ADDQ	$192, BP
 		ADDQ	$2, R10
 		SUBQ	$1, height+112(FP)
-		JNE	yloop_26
+		JNE	yloop_91
 		RET
 
-TEXT ·v8scaleNAmd64(SB),4,$0-136
+TEXT ·v8scaleNAmd64(SB),4,$8-136
 		MOVQ	dp+120(FP), BX
 		MOVQ	width+104(FP), CX
 		MOVQ	CX, DX
@@ -1322,10 +2078,10 @@ TEXT ·v8scaleNAmd64(SB),4,$0-136
 		MOVQ	CX, R12
 		MOVQ	DX, AX
 		ORQ	AX, AX
-		JE	norollback_30
+		JE	norollback_108
 		SUBQ	$16, DX
 		NEGQ	DX
-norollback_30:
+norollback_108:
 		MOVQ	DX, R13
 		MOVQ	off+72(FP), CX
 		MOVQ	CX, R10
@@ -1340,7 +2096,7 @@ norollback_30:
 		MOVQ	dst+0(FP), DI
 		MOVQ	cof+48(FP), BP
 		MOVQ	sp+128(FP), BX
-yloop_31:
+yloop_109:
 		MOVQ	R9, SI
 		MOVQ	R10, DX
 		MOVWQSX	(DX), AX
@@ -1349,8 +2105,8 @@ yloop_31:
 		MOVQ	SI, R9
 		MOVQ	R12, CX
 		ORQ	CX, CX
-		JE	nomaxloop_32
-maxloop_33:
+		JE	narrow_110
+maxloop_111:
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -1393,7 +2149,7 @@ maxloop_33:
 		MOVQ	R14, R15
 		MOVQ	BP, DX
 		ADDQ	$32, DX
-innerloop_34:
+innerloop_112:
 		ADDQ	$32, DX
 		MOVOU	(AX), X4
 		MOVOU	(AX)(BX*1), X7
@@ -1416,7 +2172,7 @@ innerloop_34:
 		PADDL	X6, X2
 		PADDL	X7, X3
 		SUBQ	$1, R15
-		JNE	innerloop_34
+		JNE	innerloop_112
 		PADDL	X13, X0
 		PADDL	X13, X1
 		PADDL	X13, X2
@@ -1432,13 +2188,12 @@ innerloop_34:
 		ADDQ	$16, SI
 		ADDQ	$16, DI
 		SUBQ	$1, CX
-		JNE	maxloop_33
-nomaxloop_32:
+		JNE	maxloop_111
 		MOVQ	R13, CX
 		SUBQ	R13, SI
 		SUBQ	R13, DI
 		ORQ	CX, CX
-		JE	nobackroll_35
+		JE	end_113
 		LEAQ	(SI)(BX*4), AX
 		MOVOU	(SI), X0
 		MOVOU	(SI)(BX*1), X3
@@ -1481,7 +2236,7 @@ nomaxloop_32:
 		MOVQ	R14, R15
 		MOVQ	BP, DX
 		ADDQ	$32, DX
-innerloop_36:
+innerloop_114:
 		ADDQ	$32, DX
 		MOVOU	(AX), X4
 		MOVOU	(AX)(BX*1), X7
@@ -1504,7 +2259,7 @@ innerloop_36:
 		PADDL	X6, X2
 		PADDL	X7, X3
 		SUBQ	$1, R15
-		JNE	innerloop_36
+		JNE	innerloop_114
 		PADDL	X13, X0
 		PADDL	X13, X1
 		PADDL	X13, X2
@@ -1519,12 +2274,611 @@ innerloop_36:
 		MOVOU	X0, (DI)
 		ADDQ	$16, SI
 		ADDQ	$16, DI
-nobackroll_35:
+		JMP	end_113
+narrow_110:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	taps+96(FP), DX
+		SHRQ	$1, DX
+		MOVQ	DX, count+-8(SP)
+loop_115:
+		PXOR	X4, X4
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_116
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X4
+skip1_116:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_117
+		SUBQ	$2, R8
+		PSLLO	$2, X4
+		PINSRW	$0, (AX)(R8*1), X4
+skip2_117:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_118
+		SUBQ	$4, R8
+		PSLLO	$4, X4
+		PINSRW	$0, (AX)(R8*1), X4
+		PINSRW	$1, 2(AX)(R8*1), X4
+skip4_118:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_119
+		PSLLO	$8, X4
+		PINSRW	$0, (AX), X4
+		PINSRW	$1, 2(AX), X4
+		PINSRW	$2, 4(AX), X4
+		PINSRW	$3, 6(AX), X4
+skip8_119:
+		ADDQ	BX, AX
+		PXOR	X7, X7
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_120
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X7
+skip1_120:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_121
+		SUBQ	$2, R8
+		PSLLO	$2, X7
+		PINSRW	$0, (AX)(R8*1), X7
+skip2_121:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_122
+		SUBQ	$4, R8
+		PSLLO	$4, X7
+		PINSRW	$0, (AX)(R8*1), X7
+		PINSRW	$1, 2(AX)(R8*1), X7
+skip4_122:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_123
+		PSLLO	$8, X7
+		PINSRW	$0, (AX), X7
+		PINSRW	$1, 2(AX), X7
+		PINSRW	$2, 4(AX), X7
+		PINSRW	$3, 6(AX), X7
+skip8_123:
+		ADDQ	BX, AX
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-8(SP)
+		JNE	loop_115
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		TESTQ	$8, width+104(FP)
+		JE	skip8_124
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_124:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_125
+		MOVQ	X0, DX
+		MOVL	DX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_125:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_126
+		MOVQ	X0, DX
+		MOVW	DX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_126:
+		TESTQ	$1, width+104(FP)
+		JE	skip1_127
+		MOVQ	X0, DX
+		MOVB	DX, (DI)
+		ADDQ	$1, DI
+skip1_127:
+end_113:
+		ADDQ	R11, DI
+		MOVQ	taps+96(FP), DX
+		SHLQ	$4, DX
+		ADDQ	DX, BP
+		ADDQ	$2, R10
+		SUBQ	$1, height+112(FP)
+		JNE	yloop_109
+		RET
+DATA	ringthr_2<>+0x00(SB)/8, $0x0200020002000200
+DATA	ringthr_2<>+0x08(SB)/8, $0x0200020002000200
+GLOBL	ringthr_2<>(SB), 8, $16
+DATA	ones_3<>+0x00(SB)/8, $0xFFFFFFFFFFFFFFFF
+DATA	ones_3<>+0x08(SB)/8, $0xFFFFFFFFFFFFFFFF
+GLOBL	ones_3<>(SB), 8, $16
+
+TEXT ·v8ringNAmd64(SB),4,$24-144
+		MOVQ	strength+136(FP), AX
+		MOVQ	$281479271743489, DX
+		IMULQ	DX
+		MOVQ	AX, strength+-16(SP)
+		MOVQ	AX, strengthhi+-8(SP)
+		MOVQ	dp+120(FP), BX
+		MOVQ	width+104(FP), CX
+		MOVQ	CX, DX
+		SUBQ	CX, BX
+		ANDQ	$15, DX
+		SHRQ	$4, CX
+		MOVQ	BX, R11
+		MOVQ	CX, R12
+		MOVQ	DX, AX
+		ORQ	AX, AX
+		JE	norollback_128
+		SUBQ	$16, DX
+		NEGQ	DX
+norollback_128:
+		MOVQ	DX, R13
+		MOVQ	off+72(FP), CX
+		MOVQ	CX, R10
+		MOVO	zero_0<>(SB), X14
+		MOVO	hbits_1<>(SB), X13
+		MOVQ	taps+96(FP), DX
+		SUBQ	$4, DX
+		SHRQ	$1, DX
+		MOVQ	DX, R14
+		MOVQ	src+24(FP), SI
+		MOVQ	SI, R9
+		MOVQ	dst+0(FP), DI
+		MOVQ	cof+48(FP), BP
+		MOVQ	sp+128(FP), BX
+yloop_129:
+		MOVQ	R9, SI
+		MOVQ	R10, DX
+		MOVWQSX	(DX), AX
+		MULQ	BX
+		ADDQ	AX, SI
+		MOVQ	SI, R9
+		MOVQ	R12, CX
+		ORQ	CX, CX
+		JE	narrow_130
+maxloop_131:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		PCMPEQB	X8, X8
+		PXOR	X9, X9
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	taps+96(FP), DX
+		SHRQ	$1, DX
+		MOVQ	DX, count+-24(SP)
+loop_132:
+		MOVOU	(AX), X4
+		ADDQ	BX, AX
+		MOVOU	(AX), X7
+		ADDQ	BX, AX
+		MOVWQSX	(R15), DX
+		CMPQ	DX, $512
+		JLE	skip_133
+		PMINUB	X4, X8
+		PMAXUB	X4, X9
+skip_133:
+		MOVWQSX	2(R15), DX
+		CMPQ	DX, $512
+		JLE	skip_134
+		PMINUB	X7, X8
+		PMAXUB	X7, X9
+skip_134:
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-24(SP)
+		JNE	loop_132
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVO	X8, X1
+		PMINUB	X9, X1
+		PCMPEQB	X8, X1
+		PMAXUB	X0, X8
+		PMINUB	X9, X8
+		PAND	X1, X8
+		PANDN	X0, X1
+		POR	X1, X8
+		MOVOU	strength+-16(SP), X5
+		MOVO	X0, X1
+		PUNPCKLBW	X14, X1
+		MOVO	X8, X2
+		PUNPCKLBW	X14, X2
+		PSUBW	X1, X2
+		MOVO	X2, X3
+		PMULHW	X5, X3
+		PMULLW	X5, X2
+		MOVO	X2, X4
+		PUNPCKLWL	X3, X2
+		PUNPCKHWL	X3, X4
+		PADDL	X13, X2
+		PADDL	X13, X4
+		PSRAL	$14, X2
+		PSRAL	$14, X4
+		PACKSSLW	X4, X2
+		PADDW	X1, X2
+		MOVO	X0, X1
+		PUNPCKHBW	X14, X1
+		MOVO	X8, X3
+		PUNPCKHBW	X14, X3
+		PSUBW	X1, X3
+		MOVO	X3, X4
+		PMULHW	X5, X4
+		PMULLW	X5, X3
+		MOVO	X3, X9
+		PUNPCKLWL	X4, X3
+		PUNPCKHWL	X4, X9
+		PADDL	X13, X3
+		PADDL	X13, X9
+		PSRAL	$14, X3
+		PSRAL	$14, X9
+		PACKSSLW	X9, X3
+		PADDW	X1, X3
+		PACKUSWB	X3, X2
+		MOVO	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$16, SI
+		ADDQ	$16, DI
+		SUBQ	$1, CX
+		JNE	maxloop_131
+		MOVQ	R13, CX
+		SUBQ	R13, SI
+		SUBQ	R13, DI
+		ORQ	CX, CX
+		JE	end_135
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		PCMPEQB	X8, X8
+		PXOR	X9, X9
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	taps+96(FP), DX
+		SHRQ	$1, DX
+		MOVQ	DX, count+-24(SP)
+loop_136:
+		MOVOU	(AX), X4
+		ADDQ	BX, AX
+		MOVOU	(AX), X7
+		ADDQ	BX, AX
+		MOVWQSX	(R15), DX
+		CMPQ	DX, $512
+		JLE	skip_137
+		PMINUB	X4, X8
+		PMAXUB	X4, X9
+skip_137:
+		MOVWQSX	2(R15), DX
+		CMPQ	DX, $512
+		JLE	skip_138
+		PMINUB	X7, X8
+		PMAXUB	X7, X9
+skip_138:
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-24(SP)
+		JNE	loop_136
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVO	X8, X1
+		PMINUB	X9, X1
+		PCMPEQB	X8, X1
+		PMAXUB	X0, X8
+		PMINUB	X9, X8
+		PAND	X1, X8
+		PANDN	X0, X1
+		POR	X1, X8
+		MOVOU	strength+-16(SP), X5
+		MOVO	X0, X1
+		PUNPCKLBW	X14, X1
+		MOVO	X8, X2
+		PUNPCKLBW	X14, X2
+		PSUBW	X1, X2
+		MOVO	X2, X3
+		PMULHW	X5, X3
+		PMULLW	X5, X2
+		MOVO	X2, X4
+		PUNPCKLWL	X3, X2
+		PUNPCKHWL	X3, X4
+		PADDL	X13, X2
+		PADDL	X13, X4
+		PSRAL	$14, X2
+		PSRAL	$14, X4
+		PACKSSLW	X4, X2
+		PADDW	X1, X2
+		MOVO	X0, X1
+		PUNPCKHBW	X14, X1
+		MOVO	X8, X3
+		PUNPCKHBW	X14, X3
+		PSUBW	X1, X3
+		MOVO	X3, X4
+		PMULHW	X5, X4
+		PMULLW	X5, X3
+		MOVO	X3, X9
+		PUNPCKLWL	X4, X3
+		PUNPCKHWL	X4, X9
+		PADDL	X13, X3
+		PADDL	X13, X9
+		PSRAL	$14, X3
+		PSRAL	$14, X9
+		PACKSSLW	X9, X3
+		PADDW	X1, X3
+		PACKUSWB	X3, X2
+		MOVO	X2, X0
+		MOVOU	X0, (DI)
+		ADDQ	$16, SI
+		ADDQ	$16, DI
+		JMP	end_135
+narrow_130:
+		PXOR	X0, X0
+		PXOR	X1, X1
+		PXOR	X2, X2
+		PXOR	X3, X3
+		PCMPEQB	X8, X8
+		PXOR	X9, X9
+		MOVQ	SI, AX
+		MOVQ	BP, R15
+		MOVQ	taps+96(FP), DX
+		SHRQ	$1, DX
+		MOVQ	DX, count+-24(SP)
+loop_139:
+		PXOR	X4, X4
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_140
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X4
+skip1_140:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_141
+		SUBQ	$2, R8
+		PSLLO	$2, X4
+		PINSRW	$0, (AX)(R8*1), X4
+skip2_141:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_142
+		SUBQ	$4, R8
+		PSLLO	$4, X4
+		PINSRW	$0, (AX)(R8*1), X4
+		PINSRW	$1, 2(AX)(R8*1), X4
+skip4_142:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_143
+		PSLLO	$8, X4
+		PINSRW	$0, (AX), X4
+		PINSRW	$1, 2(AX), X4
+		PINSRW	$2, 4(AX), X4
+		PINSRW	$3, 6(AX), X4
+skip8_143:
+		ADDQ	BX, AX
+		PXOR	X7, X7
+		MOVQ	width+104(FP), R8
+		TESTQ	$1, width+104(FP)
+		JE	skip1_144
+		SUBQ	$1, R8
+		MOVBQZX	(AX)(R8*1), DX
+		MOVQ	DX, X7
+skip1_144:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_145
+		SUBQ	$2, R8
+		PSLLO	$2, X7
+		PINSRW	$0, (AX)(R8*1), X7
+skip2_145:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_146
+		SUBQ	$4, R8
+		PSLLO	$4, X7
+		PINSRW	$0, (AX)(R8*1), X7
+		PINSRW	$1, 2(AX)(R8*1), X7
+skip4_146:
+		TESTQ	$8, width+104(FP)
+		JE	skip8_147
+		PSLLO	$8, X7
+		PINSRW	$0, (AX), X7
+		PINSRW	$1, 2(AX), X7
+		PINSRW	$2, 4(AX), X7
+		PINSRW	$3, 6(AX), X7
+skip8_147:
+		ADDQ	BX, AX
+		MOVWQSX	(R15), DX
+		CMPQ	DX, $512
+		JLE	skip_148
+		PMINUB	X4, X8
+		PMAXUB	X4, X9
+skip_148:
+		MOVWQSX	2(R15), DX
+		CMPQ	DX, $512
+		JLE	skip_149
+		PMINUB	X7, X8
+		PMAXUB	X7, X9
+skip_149:
+		MOVO	X4, X6
+		PUNPCKLBW	X7, X4
+		PUNPCKHBW	X7, X6
+		MOVO	X4, X5
+		MOVO	X6, X7
+		PUNPCKLBW	X14, X4
+		PUNPCKHBW	X14, X5
+		PUNPCKLBW	X14, X6
+		PUNPCKHBW	X14, X7
+		PMADDWL	(R15), X4
+		PMADDWL	(R15), X5
+		PMADDWL	(R15), X6
+		PMADDWL	(R15), X7
+		PADDL	X4, X0
+		PADDL	X5, X1
+		PADDL	X6, X2
+		PADDL	X7, X3
+		ADDQ	$32, R15
+		SUBQ	$1, count+-24(SP)
+		JNE	loop_139
+		PADDL	X13, X0
+		PADDL	X13, X1
+		PADDL	X13, X2
+		PADDL	X13, X3
+		PSRAL	$14, X0
+		PSRAL	$14, X1
+		PSRAL	$14, X2
+		PSRAL	$14, X3
+		PACKSSLW	X1, X0
+		PACKSSLW	X3, X2
+		PACKUSWB	X2, X0
+		MOVO	X8, X1
+		PMINUB	X9, X1
+		PCMPEQB	X8, X1
+		PMAXUB	X0, X8
+		PMINUB	X9, X8
+		PAND	X1, X8
+		PANDN	X0, X1
+		POR	X1, X8
+		MOVOU	strength+-16(SP), X5
+		MOVO	X0, X1
+		PUNPCKLBW	X14, X1
+		MOVO	X8, X2
+		PUNPCKLBW	X14, X2
+		PSUBW	X1, X2
+		MOVO	X2, X3
+		PMULHW	X5, X3
+		PMULLW	X5, X2
+		MOVO	X2, X4
+		PUNPCKLWL	X3, X2
+		PUNPCKHWL	X3, X4
+		PADDL	X13, X2
+		PADDL	X13, X4
+		PSRAL	$14, X2
+		PSRAL	$14, X4
+		PACKSSLW	X4, X2
+		PADDW	X1, X2
+		MOVO	X0, X1
+		PUNPCKHBW	X14, X1
+		MOVO	X8, X3
+		PUNPCKHBW	X14, X3
+		PSUBW	X1, X3
+		MOVO	X3, X4
+		PMULHW	X5, X4
+		PMULLW	X5, X3
+		MOVO	X3, X9
+		PUNPCKLWL	X4, X3
+		PUNPCKHWL	X4, X9
+		PADDL	X13, X3
+		PADDL	X13, X9
+		PSRAL	$14, X3
+		PSRAL	$14, X9
+		PACKSSLW	X9, X3
+		PADDW	X1, X3
+		PACKUSWB	X3, X2
+		MOVO	X2, X0
+		TESTQ	$8, width+104(FP)
+		JE	skip8_150
+		MOVQ	X0, (DI)
+		PSRLO	$8, X0
+		ADDQ	$8, DI
+skip8_150:
+		TESTQ	$4, width+104(FP)
+		JE	skip4_151
+		MOVQ	X0, DX
+		MOVL	DX, (DI)
+		PSRLO	$4, X0
+		ADDQ	$4, DI
+skip4_151:
+		TESTQ	$2, width+104(FP)
+		JE	skip2_152
+		MOVQ	X0, DX
+		MOVW	DX, (DI)
+		PSRLO	$2, X0
+		ADDQ	$2, DI
+skip2_152:
+		TESTQ	$1, width+104(FP)
+		JE	skip1_153
+		MOVQ	X0, DX
+		MOVB	DX, (DI)
+		ADDQ	$1, DI
+skip1_153:
+end_135:
 		ADDQ	R11, DI
 		MOVQ	taps+96(FP), DX
 		SHLQ	$4, DX
 		ADDQ	DX, BP
 		ADDQ	$2, R10
 		SUBQ	$1, height+112(FP)
-		JNE	yloop_31
+		JNE	yloop_129
 		RET